@@ -0,0 +1,142 @@
+package main
+
+import (
+	"fmt"
+	"image/color"
+	"math"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/ebitenutil"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+	"github.com/pankona/generic-defence-game/sim"
+)
+
+// タワーを置けるマスと置けないマスのプレビューの色
+var (
+	placeableColor   = color.RGBA{R: 0, G: 255, B: 0, A: 255}
+	unplaceableColor = color.RGBA{R: 255, G: 0, B: 0, A: 255}
+)
+
+// togglePlacingTower はタワーを置く場所を選ぶ状態を切り替える
+func (g *Game) togglePlacingTower() {
+	g.placingTower = !g.placingTower
+	g.buildingWalls = false
+}
+
+// toggleBuildingWalls は壁を引くモードを切り替える
+// 壁を引くモードの間は、ドラッグで壁を引き、ユニットの選択や移動の指示はしない
+func (g *Game) toggleBuildingWalls() {
+	g.buildingWalls = !g.buildingWalls
+	g.drawingWall = false
+	g.placingTower = false
+}
+
+// nextWallKind は次に引く壁の種類を順に切り替える
+func (g *Game) nextWallKind() {
+	kinds := sim.WallKinds()
+	for i, kind := range kinds {
+		if kind == g.wallKind {
+			g.wallKind = kinds[(i+1)%len(kinds)]
+			return
+		}
+	}
+	g.wallKind = kinds[0]
+}
+
+// updateWallDrawing は壁を引くモードでのドラッグを処理し、ドラッグを終えたときに引く壁を返す
+func (g *Game) updateWallDrawing() []sim.WallPlacement {
+	if !g.buildingWalls {
+		return nil
+	}
+	if inpututil.IsKeyJustPressed(ebiten.KeyEscape) {
+		g.toggleBuildingWalls()
+		return nil
+	}
+	if g.pointer.pressed && !g.pointer.consumed {
+		g.pointer.consume()
+		g.drawingWall = true
+	}
+	if !g.drawingWall || !g.pointer.released {
+		return nil
+	}
+	g.drawingWall = false
+	placement := g.wallPlacement()
+	if g.world.CanPlaceWall(placement) != nil {
+		return nil
+	}
+	return []sim.WallPlacement{placement}
+}
+
+// wallPlacement はドラッグを始めた位置から今の位置までの壁を返す。フィールドの外にはみ出す分は切り詰める
+func (g *Game) wallPlacement() sim.WallPlacement {
+	return sim.WallPlacement{
+		Kind: g.wallKind,
+		X1:   g.pointer.startX,
+		Y1:   g.pointer.startY,
+		X2:   math.Max(0, math.Min(g.pointer.x, sim.FieldWidth)),
+		Y2:   math.Max(0, math.Min(g.pointer.y, sim.FieldHeight)),
+	}
+}
+
+// drawWallPreview は壁を引くドラッグ中に、引こうとしている壁と長さ・コストを描画する
+// 引けない場合は赤で描画し、理由を表示する
+func (g *Game) drawWallPreview(screen *ebiten.Image) {
+	if !g.drawingWall {
+		return
+	}
+	p := g.wallPlacement()
+	clr := placeableColor
+	label := fmt.Sprintf("%dpx $%d", int(p.Length()), sim.WallCost(p.Kind, p.Length()))
+	if err := g.world.CanPlaceWall(p); err != nil {
+		clr = unplaceableColor
+		label = err.Error()
+	}
+	vector.StrokeLine(screen, float32(p.X1), float32(p.Y1), float32(p.X2), float32(p.Y2), 1, clr, false)
+	ebitenutil.DebugPrintAt(screen, label, int(p.X2)+8, int(p.Y2)+8)
+}
+
+// updateTowerPlacement はタワーを置く場所を選んでいる間のクリックを処理し、タワーを置くマスを返す
+// Shift を押しながらクリックすると、続けて次のタワーを置ける
+func (g *Game) updateTowerPlacement() []sim.TowerPlacement {
+	if !g.placingTower {
+		return nil
+	}
+	if inpututil.IsKeyJustPressed(ebiten.KeyEscape) {
+		g.placingTower = false
+		return nil
+	}
+	if !g.pointer.pressed || g.pointer.consumed {
+		return nil
+	}
+	g.pointer.consume()
+	col, row := sim.CellAt(g.pointer.x, g.pointer.y)
+	if g.world.CanPlaceTower(col, row) != nil {
+		return nil
+	}
+	if !ebiten.IsKeyPressed(ebiten.KeyShift) {
+		g.placingTower = false
+	}
+	return []sim.TowerPlacement{{Col: col, Row: row}}
+}
+
+// drawTowerPreview はタワーを置く場所を選んでいる間、カーソルのあるマスと攻撃範囲を描画する
+// 置けないマスの場合は赤で描画し、理由を表示する
+func (g *Game) drawTowerPreview(screen *ebiten.Image) {
+	if !g.placingTower || g.pointer.hoverY >= hudAreaY {
+		return
+	}
+	col, row := sim.CellAt(g.pointer.hoverX, g.pointer.hoverY)
+	x, y := sim.CellRect(col, row)
+	clr := placeableColor
+	err := g.world.CanPlaceTower(col, row)
+	if err != nil {
+		clr = unplaceableColor
+	}
+	drawRectBorder(screen, int(x), int(y), sim.BuildCellSize, sim.BuildCellSize, clr)
+	cx, cy := float32(x+sim.BuildCellSize/2), float32(y+sim.BuildCellSize/2)
+	vector.StrokeCircle(screen, cx, cy, float32(sim.TowerRange()), 1, clr, false)
+	if err != nil {
+		ebitenutil.DebugPrintAt(screen, err.Error(), int(x), int(y)+sim.BuildCellSize)
+	}
+}
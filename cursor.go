@@ -1,6 +1,10 @@
 package main
 
-import "github.com/hajimehoshi/ebiten/v2"
+import (
+	"math"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
 
 func getPointerPosition() (x, y float64, eventOccurred bool) {
 	// マウスクリックの処理
@@ -19,3 +23,68 @@ func getPointerPosition() (x, y float64, eventOccurred bool) {
 	// イベントが発生しなかった場合
 	return 0, 0, false
 }
+
+// pointer はマウスの左ボタンまたはタッチの状態をフレームをまたいで追跡する
+// 押された瞬間・離された瞬間・押され続けている状態を区別できる
+type pointer struct {
+	x, y           float64 // 最後に押されていた位置
+	startX, startY float64 // 押し始めた位置
+	hoverX, hoverY float64 // マウスカーソルの位置。タッチの場合は最後に触れていた位置
+	pressed        bool    // このフレームで押された
+	released       bool    // このフレームで離された
+	held           bool    // 押されている（押されたフレームを含む）
+
+	// UI がこの押下を処理したかどうか。次に押されるまで維持されるので、
+	// UI の上で押したままドラッグしてもワールドへの操作にはならない
+	consumed bool
+}
+
+// update はフレームの最初に呼び出し、入力デバイスの状態を取り込む
+func (p *pointer) update() {
+	x, y, held := getPointerPosition()
+	p.pressed = held && !p.held
+	p.released = !held && p.held
+	p.held = held
+	if held {
+		p.x, p.y = x, y
+		p.hoverX, p.hoverY = x, y
+	} else if len(ebiten.AppendTouchIDs(nil)) == 0 {
+		mx, my := ebiten.CursorPosition()
+		p.hoverX, p.hoverY = float64(mx), float64(my)
+	}
+	if p.pressed {
+		p.startX, p.startY = x, y
+		p.consumed = false
+	}
+}
+
+// dragged は押し始めた位置から threshold より大きく動いたかを返す
+func (p *pointer) dragged(threshold float64) bool {
+	return math.Abs(p.x-p.startX) > threshold || math.Abs(p.y-p.startY) > threshold
+}
+
+// dragRect は押し始めた位置と現在の位置を対角とする矩形を返す
+func (p *pointer) dragRect() (x0, y0, x1, y1 float64) {
+	return math.Min(p.startX, p.x), math.Min(p.startY, p.y), math.Max(p.startX, p.x), math.Max(p.startY, p.y)
+}
+
+// consume は現在の押下を UI が処理したことを記録する
+func (p *pointer) consume() {
+	p.consumed = true
+}
+
+// pressedOn はこのフレームで、まだ処理されていない押下が c の上で起きたかを返す
+func (p *pointer) pressedOn(c Clickable) bool {
+	if !p.pressed || p.consumed {
+		return false
+	}
+	cx, cy := c.GetPosition()
+	width, height := c.GetSize()
+	x, y := int(p.x), int(p.y)
+	return x >= cx && x <= cx+width && y >= cy && y <= cy+height
+}
+
+// heldInWorld は UI に処理されていない押下が続いているかを返す
+func (p *pointer) heldInWorld() bool {
+	return p.held && !p.consumed
+}
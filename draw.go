@@ -4,28 +4,142 @@ import (
 	"fmt"
 	"image"
 	"image/color"
+	"math"
 
 	"github.com/hajimehoshi/ebiten/v2"
 	"github.com/hajimehoshi/ebiten/v2/ebitenutil"
 	"github.com/hajimehoshi/ebiten/v2/vector"
+	"github.com/pankona/generic-defence-game/sim"
 )
 
 const infoAreaHeight = 120
 
+// 各ユニットの描画に使う画像
+type sprites struct {
+	player  *ebiten.Image
+	base    *ebiten.Image
+	tower   *ebiten.Image
+	bullets map[sim.ProjectileKind]*ebiten.Image // 弾の種類ごとの画像
+	enemies map[string]*ebiten.Image             // 敵の種類の ID ごとの画像
+}
+
+// 弾の種類ごとの描画色。ここにない種類は白で描画する
+var bulletColors = map[sim.ProjectileKind]color.RGBA{
+	sim.ProjectilePiercing: {R: 0, G: 255, B: 255, A: 255},   // 水色
+	sim.ProjectileSplash:   {R: 255, G: 160, B: 0, A: 255},   // 橙色
+	sim.ProjectileChain:    {R: 160, G: 160, B: 255, A: 255}, // 薄紫
+}
+
+// ステージの障害物の描画色
+var obstacleColor = color.RGBA{R: 80, G: 80, B: 80, A: 255}
+
+// 敵が入ると漏れとして数えられる領域の色
+var leakZoneColor = color.RGBA{R: 96, G: 16, B: 16, A: 96}
+
+func newSprites() *sprites {
+	player := ebiten.NewImage(16, 16)
+	player.Fill(color.White)
+	base := ebiten.NewImage(32, 32) // 本拠地の画像サイズ
+	base.Fill(color.RGBA{R: 255, G: 255, B: 0, A: 255})
+	tower := ebiten.NewImage(24, 24) // タワーの画像サイズ
+	tower.Fill(color.RGBA{R: 64, G: 128, B: 255, A: 255})
+	return &sprites{
+		player:  player,
+		base:    base,
+		tower:   tower,
+		bullets: map[sim.ProjectileKind]*ebiten.Image{},
+		enemies: map[string]*ebiten.Image{},
+	}
+}
+
+// bullet は弾の種類に応じた画像を返す。画像は初回に生成してキャッシュする
+func (s *sprites) bullet(kind sim.ProjectileKind) *ebiten.Image {
+	if img, ok := s.bullets[kind]; ok {
+		return img
+	}
+	clr, ok := bulletColors[kind]
+	if !ok {
+		clr = color.RGBA{R: 255, G: 255, B: 255, A: 255}
+	}
+	img := ebiten.NewImage(4, 4)
+	img.Fill(clr)
+	s.bullets[kind] = img
+	return img
+}
+
+// enemy は敵の種類に応じた画像を返す。画像は初回に生成してキャッシュする
+func (s *sprites) enemy(archetype *sim.EnemyArchetype) *ebiten.Image {
+	if img, ok := s.enemies[archetype.ID]; ok {
+		return img
+	}
+	size := int(archetype.Size)
+	img := ebiten.NewImage(size, size)
+	img.Fill(archetype.RGBA())
+	s.enemies[archetype.ID] = img
+	return img
+}
+
+func drawSprite(screen, img *ebiten.Image, x, y float64) {
+	op := &ebiten.DrawImageOptions{}
+	op.GeoM.Translate(x, y)
+	screen.DrawImage(img, op)
+}
+
+// 壁の種類ごとの色と線の太さ
+var wallStyles = map[sim.WallKind]struct {
+	color color.RGBA
+	width float32
+}{
+	sim.WallSlow:     {color.RGBA{R: 150, G: 150, B: 150, A: 255}, 1}, // 灰色の細い線
+	sim.WallDamage:   {color.RGBA{R: 255, G: 120, B: 0, A: 255}, 2},   // 橙色の線
+	sim.WallBlock:    {color.RGBA{R: 255, G: 255, B: 255, A: 255}, 4}, // 白い太い線
+	sim.WallRedirect: {color.RGBA{R: 0, G: 200, B: 255, A: 255}, 2},   // 水色の線に向きを表す矢印
+}
+
+// drawWall は壁を種類ごとの見た目で描画する。耐久度が減るほど薄く描画する
+func drawWall(screen *ebiten.Image, wall *sim.Wall) {
+	style := wallStyles[wall.Kind()]
+	alpha := 0.3 + 0.7*float64(wall.HP())/float64(wall.MaxHP())
+	clr := color.RGBA{
+		R: uint8(float64(style.color.R) * alpha),
+		G: uint8(float64(style.color.G) * alpha),
+		B: uint8(float64(style.color.B) * alpha),
+		A: uint8(255 * alpha),
+	}
+	x1, y1, x2, y2 := wall.Endpoints()
+	vector.StrokeLine(screen, float32(x1), float32(y1), float32(x2), float32(y2), style.width, clr, false)
+
+	if wall.Kind() != sim.WallRedirect {
+		return
+	}
+	// 敵を押し流す向きに 20px ごとに矢印を描く
+	length := math.Hypot(x2-x1, y2-y1)
+	if length == 0 {
+		return
+	}
+	dx, dy := (x2-x1)/length, (y2-y1)/length
+	const arrowSize = 5
+	for d := 10.0; d < length; d += 20 {
+		x, y := x1+dx*d, y1+dy*d
+		for _, side := range []float64{-1, 1} {
+			ax := x - dx*arrowSize - dy*arrowSize*side
+			ay := y - dy*arrowSize + dx*arrowSize*side
+			vector.StrokeLine(screen, float32(ax), float32(ay), float32(x), float32(y), 1, clr, false)
+		}
+	}
+}
+
 func drawMoney(screen *ebiten.Image, money int) {
-	ebitenutil.DebugPrintAt(screen, fmt.Sprintf("Money: %d", money), screenWidth-100, 10)
+	ebitenutil.DebugPrintAt(screen, fmt.Sprintf("Money: %d", money), hudRightX, hudAreaY+4)
 }
 
-func drawGameOver(screen *ebiten.Image) {
-	const message = "Game Over"
-	messageWidth := len(message) * 6 // 6 is the width of a character
-	messageX := (screenWidth - messageWidth) / 2
-	messageY := (screenHeight - infoAreaHeight) / 2
-	ebitenutil.DebugPrintAt(screen, message, messageX, messageY)
+// drawInk はこのウェーブで引ける壁の長さの残りを表示する
+func drawInk(screen *ebiten.Image, ink float64) {
+	ebitenutil.DebugPrintAt(screen, fmt.Sprintf("Ink: %d/%d", int(ink), sim.WallInkPerWave), hudRightX, hudAreaY+20)
 }
 
-func drawWaiting(screen *ebiten.Image) {
-	const message = "Click to Start"
+func drawGameOver(screen *ebiten.Image) {
+	const message = "Game Over"
 	messageWidth := len(message) * 6 // 6 is the width of a character
 	messageX := (screenWidth - messageWidth) / 2
 	messageY := (screenHeight - infoAreaHeight) / 2
@@ -49,28 +163,82 @@ const infoAreaX = sideMargin
 func (g *Game) drawUnitInfo(screen *ebiten.Image, unit Clickable) {
 	// 情報表示領域のX座標
 	switch u := unit.(type) {
-	case *Player:
+	case *sim.Player:
 		ebitenutil.DebugPrintAt(screen, "Player", infoAreaX+sideMargin, infoAreaY+marginBottom)
-	case *Enemy:
-		ebitenutil.DebugPrintAt(screen, "Enemy", infoAreaX+sideMargin, infoAreaY+marginBottom)
-		ebitenutil.DebugPrintAt(screen, fmt.Sprintf("HP: %d", u.HP), infoAreaX+sideMargin, infoAreaY+marginBottom+20) // EnemyのHPを表示
-	case *Base:
+		ebitenutil.DebugPrintAt(screen, withEffects(fmt.Sprintf("HP: %d/%d", u.HP, u.MaxHP()), u.Effects()), infoAreaX+sideMargin, infoAreaY+marginBottom+20)
+		weapon := u.Weapon()
+		ebitenutil.DebugPrintAt(screen, fmt.Sprintf("ATK: %d RNG: %d", weapon.Damage, int(weapon.Range)), infoAreaX+sideMargin, infoAreaY+marginBottom+40)
+		ebitenutil.DebugPrintAt(screen, fmt.Sprintf("Order: %s", orderName(u.OrderKind())), infoAreaX+sideMargin, infoAreaY+marginBottom+60)
+	case *sim.Tower:
+		ebitenutil.DebugPrintAt(screen, "Tower", infoAreaX+sideMargin, infoAreaY+marginBottom)
+		weapon := u.Weapon()
+		ebitenutil.DebugPrintAt(screen, fmt.Sprintf("ATK: %d RNG: %d", weapon.Damage, int(weapon.Range)), infoAreaX+sideMargin, infoAreaY+marginBottom+20)
+	case *sim.Enemy:
+		ebitenutil.DebugPrintAt(screen, u.Archetype().Name, infoAreaX+sideMargin, infoAreaY+marginBottom)
+		ebitenutil.DebugPrintAt(screen, withEffects(fmt.Sprintf("HP: %d", u.HP), u.Effects()), infoAreaX+sideMargin, infoAreaY+marginBottom+20) // EnemyのHPを表示
+	case *sim.Base:
 		g.drawBaseInfo(screen)
 	}
 
+	g.drawButtons(screen)
+}
+
+// withEffects は受けている状態効果があれば、その名前を s の後ろに並べる
+func withEffects(s string, effects []sim.EffectID) string {
+	for _, effect := range effects {
+		s += " " + string(effect)
+	}
+	return s
+}
+
+// drawButtons は情報パネルのボタンを描画する
+func (g *Game) drawButtons(screen *ebiten.Image) {
+	if g.unitInfoPanel == nil {
+		return
+	}
 	for _, button := range g.unitInfoPanel.buttons {
-		x, y := int(button.x), int(button.y)
-		drawRectBorder(screen, x, y, 100, infoAreaHeight-10, color.White)
-		for _, text := range button.text {
-			ebitenutil.DebugPrintAt(screen, text, x+10, y+10)
-			y += 20
+		g.drawButton(screen, button)
+	}
+}
+
+// drawButton はボタンを描画する。地面のクリックを待っている指示のボタンと、有効になっているモードのボタンは緑で囲む
+func (g *Game) drawButton(screen *ebiten.Image, button *Button) {
+	x, y := int(button.x), int(button.y)
+	var clr color.Color = color.White
+	if button.order != "" && button.order == g.pendingOrder || button.active != nil && button.active(g) {
+		clr = selectionColor
+	}
+	drawRectBorder(screen, x, y, int(button.width), int(button.height), clr)
+	text := button.text
+	if button.label != nil {
+		if label := button.label(g); label != "" {
+			text = append(text[:len(text):len(text)], label)
 		}
 	}
+	for _, text := range text {
+		ebitenutil.DebugPrintAt(screen, text, x+10, y+10)
+		y += 20
+	}
+}
+
+// orderName はユニットが従っている指示の表示名を返す
+func orderName(kind sim.OrderKind) string {
+	if kind == "" {
+		return "idle"
+	}
+	return string(kind)
 }
 
 func (g *Game) drawBaseInfo(screen *ebiten.Image) { // 情報表示領域のX座標
 	ebitenutil.DebugPrintAt(screen, "Base", infoAreaX+sideMargin, infoAreaY+marginBottom)
-	ebitenutil.DebugPrintAt(screen, fmt.Sprintf("HP: %d", g.base.HP), infoAreaX+sideMargin, infoAreaY+marginBottom+20)
+	ebitenutil.DebugPrintAt(screen, withEffects(fmt.Sprintf("HP: %d", g.world.Base().HP), g.world.Base().Effects()), infoAreaX+sideMargin, infoAreaY+marginBottom+20)
+
+	// 復活待ちのユニットがいれば、次に復活するまでの時間と、すぐに復活させるためのお金を表示する
+	if timers := g.world.RespawnTimers(); len(timers) > 0 {
+		ebitenutil.DebugPrintAt(screen, fmt.Sprintf("Respawning: %d", len(timers)), infoAreaX+sideMargin, infoAreaY+marginBottom+40)
+		ebitenutil.DebugPrintAt(screen, fmt.Sprintf("Next: %s", formatTicks(timers[0]+59)), infoAreaX+sideMargin, infoAreaY+marginBottom+60)
+		ebitenutil.DebugPrintAt(screen, fmt.Sprintf("Now: $%d", g.world.RespawnCost()), infoAreaX+sideMargin, infoAreaY+marginBottom+80)
+	}
 
 	/*
 		recoverButton := &RecoverButton{
@@ -94,28 +262,50 @@ func drawRectBorder(screen *ebiten.Image, x, y, width, height int, clr color.Col
 }
 
 func (g *Game) drawGame(screen *ebiten.Image) {
-	drawMoney(screen, g.money)
+	g.drawHUD(screen)
+	for _, button := range g.hudButtons {
+		g.drawButton(screen, button)
+	}
 
-	for _, player := range g.players {
-		player.Draw(screen)
+	if zone := g.world.Stage().LoseConditions.LeakZone; zone != nil {
+		vector.DrawFilledRect(screen, float32(zone.X), float32(zone.Y), float32(zone.Width), float32(zone.Height), leakZoneColor, false)
+	}
+	for _, obstacle := range g.world.Stage().Obstacles {
+		vector.DrawFilledRect(screen, float32(obstacle.X), float32(obstacle.Y), float32(obstacle.Width), float32(obstacle.Height), obstacleColor, false)
+	}
+	for _, tower := range g.world.Towers() {
+		drawSprite(screen, g.sprites.tower, tower.GetX(), tower.GetY())
+	}
+	for _, player := range g.world.Players() {
+		drawSprite(screen, g.sprites.player, player.GetX(), player.GetY())
 	}
-	for _, enemy := range g.enemies {
-		enemy.Draw(screen)
+	for _, enemy := range g.world.Enemies() {
+		drawSprite(screen, g.sprites.enemy(enemy.Archetype()), enemy.GetX(), enemy.GetY())
 	}
-	for _, bullet := range g.playerBullets {
-		bullet.Draw(screen)
+	for _, bullet := range g.world.PlayerBullets() {
+		drawSprite(screen, g.sprites.bullet(bullet.Kind()), bullet.GetX(), bullet.GetY())
 	}
-	for _, bullet := range g.enemyBullets {
-		bullet.Draw(screen)
+	for _, bullet := range g.world.EnemyBullets() {
+		drawSprite(screen, g.sprites.bullet(bullet.Kind()), bullet.GetX(), bullet.GetY())
 	}
-	for _, wall := range g.walls {
-		wall.Draw(screen)
+	for _, wall := range g.world.Walls() {
+		drawWall(screen, &wall)
 	}
-	if g.unitInfo != nil {
-		g.drawUnitInfo(screen, g.unitInfo)
+	g.drawSelection(screen)
+	g.drawTowerPreview(screen)
+	g.drawWallPreview(screen)
+	// 倒された敵など、ワールドから消えたユニットの情報は表示しない
+	if players := g.selectedPlayers(); len(players) > 1 {
+		drawGroupInfo(screen, players)
+		g.drawButtons(screen)
+	} else if len(g.selection) == 1 {
+		if unit, ok := g.world.Entity(g.selection[0]).(Clickable); ok {
+			g.drawUnitInfo(screen, unit)
+		}
 	}
 
-	g.base.Draw(screen)
+	base := g.world.Base()
+	drawSprite(screen, g.sprites.base, base.GetX(), base.GetY())
 }
 
 func drawInfoArea(screen *ebiten.Image) {
@@ -154,16 +344,5 @@ func drawInfoArea(screen *ebiten.Image) {
 
 func (g *Game) Draw(screen *ebiten.Image) {
 	screen.Fill(color.Black)
-
-	switch g.gameState {
-	case Waiting:
-		drawWaiting(screen)
-		return
-	case GameOver:
-		drawGameOver(screen)
-	case GameClear:
-		drawGameClear(screen)
-	}
-	g.drawGame(screen)
-	drawInfoArea(screen)
+	g.state.draw(g, screen)
 }
@@ -1,32 +1,35 @@
 package main
 
 import (
-	"image/color"
-	"math"
+	"fmt"
+	"time"
 
 	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+	"github.com/pankona/generic-defence-game/sim"
 )
 
 type Game struct {
-	players        []Player
-	enemies        []Enemy
-	playerBullets  []Bullet
-	enemyBullets   []Bullet
-	gameState      string
-	spawnInterval  int
-	spawnedEnemies int
-	currentWave    int
-	currentStage   Stage
-	maxEnemies     int
-	isDragging     bool
-	startX, startY float64
-	walls          []Wall
-	reachedEnemies int
-	money          int
-	base           *Base
-
-	// 情報パネルに表示するユニットを保持
-	unitInfo      Clickable
+	world      *sim.World
+	recorder   *sim.Recorder // バグ報告の再現用に入力を記録する
+	playback   *playbackView // リプレイの再生中のみ設定される
+	pointer    pointer
+	sprites    *sprites
+	state      gameState
+	hudButtons []*Button      // 情報パネルとは別に常に表示するボタン
+	speed      int            // 1 フレームあたりに進めるティック数。gameSpeeds のいずれか
+	bestWaves  map[string]int // ステージ ID ごとの片付けたウェーブの数の最高記録。ゲームを終了するまで保持する
+
+	extraStages []sim.Stage // -stage で読み込んだ、同梱されていないステージ。ステージ選択で同梱のステージの後に並べる
+
+	// 選択中のユニットの ID。プレイヤーユニットは複数選択できる
+	selection     []sim.EntityID
+	boxSelecting  bool          // 範囲選択のドラッグ中かどうか
+	pendingOrder  sim.OrderKind // 次に地面をクリックしたときに出す指示。空の場合は移動
+	placingTower  bool          // タワーを置く場所を選んでいるかどうか
+	buildingWalls bool          // 壁を引くモードかどうか
+	drawingWall   bool          // 壁を引くドラッグ中かどうか
+	wallKind      sim.WallKind  // 次に引く壁の種類
 	unitInfoPanel *UnitInfoPanel
 }
 
@@ -38,7 +41,11 @@ type UnitInfoPanel struct {
 }
 
 type Button struct {
-	id                  string
+	command             sim.Command          // 押したときに発行するコマンド
+	order               sim.OrderKind        // 押したときに選択中のユニットに出す指示
+	action              func(g *Game)        // 押したときに実行する画面側の処理
+	active              func(g *Game) bool   // 押した結果のモードが有効な間 true を返す。有効な間は緑で囲む
+	label               func(g *Game) string // 今の状態に応じて最後の行に表示する文字列を返す
 	x, y, width, height float64
 	text                []string
 }
@@ -67,261 +74,196 @@ type Clickable interface {
 	GetSize() (width, height int)
 }
 
-const (
-	Waiting   = "waiting"
-	Playing   = "playing"
-	GameOver  = "gameover"
-	GameClear = "gameclear"
-)
-
-func NewGame() *Game {
-	arrow := ebiten.NewImage(16, 16)
-	arrow.Fill(color.White)
-	return &Game{
-		players:      []Player{NewPlayer()},
-		maxEnemies:   10,
-		gameState:    Waiting,
-		base:         NewBase(),
-		currentStage: sampleStage,
-		money:        100, // debug
-	}
-}
-
-type Position struct {
-	X, Y int
-}
-
-func (g *Game) getInputPositions() []Position {
-	positions := []Position{}
-	if ebiten.IsMouseButtonPressed(ebiten.MouseButtonLeft) {
-		x, y := ebiten.CursorPosition()
-		positions = append(positions, Position{X: x, Y: y})
-	}
-	for _, id := range ebiten.AppendTouchIDs(nil) {
-		x, y := ebiten.TouchPosition(id)
-		positions = append(positions, Position{X: x, Y: y})
+// NewGame は stage を遊ぶ準備をしたゲームを、タイトル画面の状態で生成する
+func NewGame(stage sim.Stage) *Game {
+	g := &Game{
+		sprites: newSprites(),
+		hudButtons: []*Button{
+			{
+				action: (*Game).toggleBuildingWalls,
+				active: func(g *Game) bool { return g.buildingWalls },
+				text:   []string{"Walls [W]"},
+				x:      10,
+				y:      hudButtonY,
+				width:  80,
+				height: 36,
+			},
+			{
+				action: (*Game).nextWallKind,
+				label:  func(g *Game) string { return string(g.wallKind) },
+				text:   []string{"Kind [K]"},
+				x:      100,
+				y:      hudButtonY,
+				width:  80,
+				height: 36,
+			},
+			{
+				action: (*Game).togglePause,
+				active: func(g *Game) bool { _, ok := g.state.(*pausedState); return ok },
+				text:   []string{"Pause [P]"},
+				x:      190,
+				y:      hudButtonY,
+				width:  80,
+				height: 36,
+			},
+			{
+				action: (*Game).nextSpeed,
+				label:  func(g *Game) string { return fmt.Sprintf("%dx", g.speed) },
+				text:   []string{"Speed [F]"},
+				x:      280,
+				y:      hudButtonY,
+				width:  80,
+				height: 36,
+			},
+			{
+				command: sim.CommandNextWave,
+				label:   nextWaveLabel,
+				text:    []string{"Next [N]"},
+				x:       370,
+				y:       hudButtonY,
+				width:   80,
+				height:  36,
+			},
+		},
+		speed:    gameSpeeds[0],
+		wallKind: sim.WallSlow,
 	}
-	return positions
+	g.reset(stage)
+	g.setState(&titleState{})
+	return g
 }
 
-// isUnitClicked関数をリファクタリング
-func (g *Game) isUnitClicked(unit Clickable) bool {
-	for _, pos := range g.getInputPositions() {
-		unitX, unitY := unit.GetPosition()
-		unitWidth, unitHeight := unit.GetSize()
-		if pos.X >= unitX && pos.X <= unitX+unitWidth && pos.Y >= unitY && pos.Y <= unitY+unitHeight {
-			return true
-		}
+// reset は stage を最初から遊べるように、ワールドと操作の状態を作り直す
+func (g *Game) reset(stage sim.Stage) {
+	// 途中でやめたプレイも最高記録に含める
+	if g.world != nil {
+		g.recordBestWave()
 	}
-	return false
+	seed := time.Now().UnixNano()
+	g.world = sim.NewWorld(stage, seed)
+	g.recorder = sim.NewRecorder(stage.ID, seed)
+	g.pendingOrder = ""
+	g.placingTower, g.buildingWalls, g.drawingWall = false, false, false
+	// 最初のユニットは選択した状態で始める
+	g.selectUnits([]sim.EntityID{g.world.Players()[0].ID()})
 }
 
 func (g *Game) UpdateGame() {
-	// 敵の生成
-	if g.currentWave < len(g.currentStage.Waves) {
-		wave := g.currentStage.Waves[g.currentWave]
+	in := sim.Input{}
 
-		// 敵をスポーンさせるか確認
-		for _, spawnInfo := range wave.EnemySpawns {
-			if spawnInfo.SpawnFrame == g.spawnInterval {
-				g.enemies = append(g.enemies, NewEnemyA(0, 0))
-			}
+	// UI の当たり判定を先に行い、UI が処理したクリックはワールドへの操作にしない
+	for _, button := range g.hudButtons {
+		if !g.pointer.pressedOn(button) {
+			continue
 		}
-		g.spawnInterval++
-
-		// ウェーブが終了したか確認
-		if g.spawnInterval >= wave.TotalFrames {
-			g.currentWave++
-			g.spawnInterval = 0
+		g.pointer.consume()
+		if button.action != nil {
+			button.action(g)
+		}
+		if button.command != "" {
+			in.Commands = append(in.Commands, button.command)
 		}
 	}
-
-	// ゲームオーバーの判定
-	if g.reachedEnemies >= 3 {
-		g.gameState = GameOver
-	}
-
-	// すべてのウェーブが終了し、敵が全滅したときの処理（クリア）
-	if g.currentWave >= len(g.currentStage.Waves) && len(g.enemies) == 0 {
-		g.gameState = GameClear
+	// 一時停止のボタンが押された場合はワールドを進めない
+	if _, ok := g.state.(*playingState); !ok {
+		return
 	}
-
-	// 敵全体に対する処理
-	for i := range g.enemies {
-		enemy := &g.enemies[i]
-
-		if g.isUnitClicked(enemy) {
-			g.unitInfo = enemy
-			g.unitInfoPanel = NewUnitInfoPanel(enemy)
-		}
-
-		// 右下に到達した敵に対する処理
-		if enemy.reached {
-			g.reachedEnemies++
-			enemy.reached = false
-			enemy.active = false
-		}
-
-		// プレイヤーが敵に近づいたら自動的に攻撃する
-		for i := range g.players {
-			player := &g.players[i]
-			distX := player.x - enemy.x
-			distY := player.y - enemy.y
-			distance := distX*distX + distY*distY
-
-			// プレイヤーの攻撃範囲
-			attackRange := 100.0 * 100.0
-			if distance < attackRange && player.framesSinceLastBullet >= player.bulletFrameInterval {
-				// 弾を発射する
-				bullet := NewBullet(player.x, player.y, enemy)
-				g.playerBullets = append(g.playerBullets, bullet)
-
-				player.framesSinceLastBullet = 0
+	if g.unitInfoPanel != nil {
+		for _, button := range g.unitInfoPanel.buttons {
+			if !g.pointer.pressedOn(button) {
+				continue
 			}
-		}
-
-		// base に到達した敵に対する処理
-		{
-			distX := g.base.x - enemy.x
-			distY := g.base.y - enemy.y
-			distance := distX*distX + distY*distY
-
-			// 敵の攻撃範囲
-			attackRange := 100.0 * 100.0
-			// 敵の攻撃範囲に base が入っていたら攻撃を開始する。そうでなければ base を目指す。
-			if distance < attackRange {
-				if enemy.framesSinceLastBullet >= enemy.bulletFrameInterval {
-					// 弾を発射する
-					bullet := NewBullet(enemy.x, enemy.y, g.base)
-					g.enemyBullets = append(g.enemyBullets, bullet)
-
-					enemy.framesSinceLastBullet = 0
-				}
-			} else {
-				// ベースをターゲットにする
-				dx := g.base.x - enemy.x
-				dy := g.base.y - enemy.y
-				dist := math.Sqrt(dx*dx + dy*dy)
-
-				// 速度を正規化
-				if dist > 0 {
-					dx /= dist
-					dy /= dist
-				}
-
-				// 敵を移動
-				enemy.x += dx * enemy.speed
-				enemy.y += dy * enemy.speed
+			g.pointer.consume()
+			if button.action != nil {
+				button.action(g)
+			}
+			// ボタンに応じたコマンドを発行する
+			if button.command != "" {
+				in.Commands = append(in.Commands, button.command)
+			}
+			// ボタンに応じた指示を出す。移動先が必要な指示は次に地面をクリックしたときに出す
+			switch button.order {
+			case "":
+			case sim.OrderHold:
+				in.Orders = append(in.Orders, sim.Order{Kind: sim.OrderHold, Units: g.selectedPlayerIDs()})
+				g.pendingOrder = ""
+			case sim.OrderTargeting:
+				in.Orders = append(in.Orders, sim.Order{Kind: sim.OrderTargeting, Units: g.selection, Targeting: g.nextTargeting()})
+			default:
+				g.pendingOrder = button.order
 			}
 		}
-
-		enemy.Update(g)
 	}
-
-	for i := range g.players {
-		player := &g.players[i]
-		player.Update(g)
-		if g.isUnitClicked(player) {
-			g.unitInfo = player
-			g.unitInfoPanel = NewUnitInfoPanel(player)
-		}
+	// HUD の帯と情報表示領域の中のクリックもワールドへの操作にしない
+	if g.pointer.pressed && g.pointer.y >= hudAreaY {
+		g.pointer.consume()
 	}
 
-	if g.isUnitClicked(g.base) {
-		g.unitInfo = g.base
-		g.unitInfoPanel = NewUnitInfoPanel(g.base)
-		g.unitInfoPanel.SetButtons([]*Button{
-			{
-				id:     "recover_hp", // TODO: この id はユニークにする必要がある。const にする。
-				text:   []string{"Recover HP", "+10HP / $10"},
-				x:      infoAreaX + sideMargin + 100,
-				y:      infoAreaY + 5,
-				width:  100,
-				height: infoAreaHeight - 10,
-			},
-			{
-				id:     "train_unit", // TODO: この id はユニークにする必要がある。const にする。
-				text:   []string{"Train Unit", "$100"},
-				x:      infoAreaX + sideMargin + 200 + 5,
-				y:      infoAreaY + 5,
-				width:  100,
-				height: infoAreaHeight - 10,
-			},
-		})
+	// タワーの配置と壁の作成
+	if inpututil.IsKeyJustPressed(ebiten.KeyW) {
+		g.toggleBuildingWalls()
+	}
+	if inpututil.IsKeyJustPressed(ebiten.KeyK) {
+		g.nextWallKind()
 	}
 
-	if g.unitInfoPanel != nil {
-		for i := range g.unitInfoPanel.buttons {
-			if g.isUnitClicked(g.unitInfoPanel.buttons[i]) {
-				// ボタンに応じたイベントを発行する
-				switch g.unitInfoPanel.buttons[i].id {
-				case "recover_hp":
-					g.base.recoverHP(g)
-				case "train_unit":
-					g.base.trainUnit(g)
-				}
-			}
-		}
+	// 次のウェーブを早めに呼ぶ
+	if inpututil.IsKeyJustPressed(ebiten.KeyN) {
+		in.Commands = append(in.Commands, sim.CommandNextWave)
 	}
 
-	// プレイヤーの弾の更新と敵との当たり判定
-	for i := range g.playerBullets {
-		bullet := &g.playerBullets[i]
-		bullet.Update()
-		for i := range g.enemies {
-			enemy := &g.enemies[i]
-			if bullet.active && enemy.IsHit(bullet.x, bullet.y) {
-				bullet.active = false
-				enemy.HP -= 1 // TODO: 攻撃力は弾、もしくは武器に持たせる
-				if enemy.HP <= 0 {
-					enemy.active = false
-					g.money += enemy.reward
-				}
-			}
+	// ゲームの速度の切り替え
+	if inpututil.IsKeyJustPressed(ebiten.KeyF) {
+		g.nextSpeed()
+	}
+	for i, key := range []ebiten.Key{ebiten.KeyDigit1, ebiten.KeyDigit2, ebiten.KeyDigit3} {
+		if inpututil.IsKeyJustPressed(key) {
+			g.speed = gameSpeeds[i]
 		}
 	}
+	in.Towers = g.updateTowerPlacement()
+	in.Walls = g.updateWallDrawing()
 
-	// 敵の弾の更新と敵との当たり判定
-	for i := range g.enemyBullets {
-		bullet := &g.enemyBullets[i]
-		bullet.Update()
-		if bullet.active && g.base.IsHit(bullet.x, bullet.y) {
-			bullet.active = false
-			g.base.HP -= 1 // TODO: 敵の攻撃力を設定できるようにする
-			if g.base.HP <= 0 {
-				g.gameState = GameOver
-			}
-		}
+	// ユニットの選択と、選択中のユニットへの移動指示
+	in.Orders = append(in.Orders, g.updateSelection()...)
+
+	// 速度に応じて 1 フレームに複数ティック進める。入力は最初のティックで与える
+	for i := 0; i < g.speed && g.world.Status() == sim.Running; i++ {
+		g.recorder.Record(g.world.Tick(), in)
+		g.world.Step(in)
+		in = sim.Input{}
 	}
+}
 
-	// 無効になった敵を削除
-	activeEnemies := g.enemies[:0]
-	for _, enemy := range g.enemies {
-		if enemy.active {
-			activeEnemies = append(activeEnemies, enemy)
+// ゲームの速度の選択肢。キーの 1, 2, 3 に対応する
+var gameSpeeds = []int{1, 2, 4}
+
+// nextSpeed はゲームの速度を順に切り替える
+func (g *Game) nextSpeed() {
+	for i, speed := range gameSpeeds {
+		if speed == g.speed {
+			g.speed = gameSpeeds[(i+1)%len(gameSpeeds)]
+			return
 		}
 	}
-	g.enemies = activeEnemies
+	g.speed = gameSpeeds[0]
+}
 
-	// 無効になった弾を削除
-	{
-		activeBullets := g.playerBullets[:0]
-		for _, bullet := range g.playerBullets {
-			if bullet.active {
-				activeBullets = append(activeBullets, bullet)
-			}
-		}
-		g.playerBullets = activeBullets
+// nextWaveLabel は次のウェーブを今すぐ呼んだときにもらえるお金を返す。今は呼べない場合は空文字列を返す
+func nextWaveLabel(g *Game) string {
+	if !g.world.CanCallNextWave() {
+		return ""
 	}
-	// 無効になった弾を削除
-	{
-		activeBullets := g.enemyBullets[:0]
-		for _, bullet := range g.enemyBullets {
-			if bullet.active {
-				activeBullets = append(activeBullets, bullet)
-			}
-		}
-		g.enemyBullets = activeBullets
+	return fmt.Sprintf("+$%d", g.world.NextWaveBonus())
+}
+
+// togglePause はプレイ中のゲームを一時停止し、一時停止中であれば再開する
+func (g *Game) togglePause() {
+	switch s := g.state.(type) {
+	case *playingState:
+		g.setState(&pausedState{resume: s})
+	case *pausedState:
+		g.setState(s.resume)
 	}
 }
 
@@ -330,46 +272,7 @@ func (g *Game) Layout(outsideWidth, outsideHeight int) (int, int) {
 }
 
 func (g *Game) Update() error {
-	// マウスの左クリックまたはタッチイベントが発生した場合
-	if ebiten.IsMouseButtonPressed(ebiten.MouseButtonLeft) || len(ebiten.AppendTouchIDs(nil)) > 0 {
-		// ゲーム開始待機状態の場合、ゲームを開始
-		if g.gameState == Waiting {
-			g.gameState = Playing
-			return nil
-		}
-
-		// ゲームオーバーまたはゲームクリアの状態の場合、ゲームをリセット
-		if g.gameState == GameOver || g.gameState == GameClear {
-			*g = *NewGame()
-			return nil
-		}
-	}
-
-	if g.gameState == Playing {
-		g.UpdateGame()
-	}
-
-	/*
-		// TODO: スマホでの操作を考慮し、タッチイベントを使用して壁の生成を実装する
-		// 壁の生成に関するロジック
-		if ebiten.IsMouseButtonPressed(ebiten.MouseButtonLeft) {
-			if !g.isDragging {
-				g.isDragging = true
-				x, y := ebiten.CursorPosition()
-				g.startX, g.startY = float64(x), float64(y)
-			}
-		} else if g.isDragging {
-			x, y := ebiten.CursorPosition()
-			endX, endY := float64(x), float64(y)
-			// 壁の長さが短すぎる場合は壁を生成しない
-			if math.Abs(endX-g.startX) < 10 && math.Abs(endY-g.startY) < 10 {
-				g.isDragging = false
-				return nil
-			}
-			g.walls = append(g.walls, Wall{id: uuid.New().String(), x1: g.startX, y1: g.startY, x2: endX, y2: endY})
-			g.isDragging = false
-		}
-	*/
-
+	g.pointer.update()
+	g.state.update(g)
 	return nil
 }
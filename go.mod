@@ -2,10 +2,7 @@ module github.com/pankona/generic-defence-game
 
 go 1.21.0
 
-require (
-	github.com/google/uuid v1.3.1
-	github.com/hajimehoshi/ebiten/v2 v2.5.9
-)
+require github.com/hajimehoshi/ebiten/v2 v2.5.9
 
 require (
 	github.com/ebitengine/purego v0.4.0 // indirect
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"fmt"
+	"image/color"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/ebitenutil"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+	"github.com/pankona/generic-defence-game/sim"
+)
+
+// HUD の表示位置。HUD はフィールドと情報表示領域の間の帯に表示し、フィールドには重ねない
+const (
+	hudAreaY      = sim.FieldHeight   // HUD の帯の Y 座標
+	hudAreaHeight = 70                // HUD の帯の高さ
+	hudButtonY    = hudAreaY + 6      // HUD のボタンを並べる行
+	hudStatusY    = hudAreaY + 48     // ウェーブの進み具合を表示する行。HUD のボタンの下
+	hudRightX     = screenWidth - 100 // お金などを表示する列
+)
+
+// 本拠地の HP バーの大きさと色
+const (
+	baseHPBarWidth  = 90
+	baseHPBarHeight = 6
+)
+
+var (
+	baseHPColor    = color.RGBA{R: 0, G: 200, B: 0, A: 255}
+	baseHPLowColor = color.RGBA{R: 220, G: 0, B: 0, A: 255}
+)
+
+// drawHUD はプレイ中に常に表示する情報を描画する
+func (g *Game) drawHUD(screen *ebiten.Image) {
+	drawMoney(screen, g.world.Money())
+	drawInk(screen, g.world.Ink())
+	g.drawBaseHP(screen)
+	g.drawWaveStatus(screen)
+}
+
+// drawWaveStatus はウェーブの進み具合・残りの敵の数・本拠地に到達した敵の数を表示する
+func (g *Game) drawWaveStatus(screen *ebiten.Image) {
+	w := g.world
+	var status string
+	if w.Endless() {
+		status = fmt.Sprintf("Wave %d (Best %d)", w.Wave(), g.bestWave())
+	} else {
+		status = fmt.Sprintf("Wave %d/%d", w.Wave(), len(w.Stage().Waves))
+	}
+	if ticks, ok := w.NextWaveIn(); ok {
+		status += fmt.Sprintf("  Next: %s", formatTicks(ticks+59)) // 残り 1 秒未満を 0 秒と表示しないよう切り上げる
+	} else {
+		status += "  Final wave"
+	}
+	status += fmt.Sprintf("  Enemies: %d", len(w.Enemies()))
+	if pending := w.PendingSpawns(); pending > 0 {
+		status += fmt.Sprintf(" (+%d)", pending)
+	}
+	// 防衛線を抜ける領域がないステージでは漏れを数えないので表示しない
+	if lose := w.Stage().LoseConditions; lose.LeakZone != nil && lose.MaxReachedEnemies > 0 {
+		status += fmt.Sprintf("  Leaks: %d/%d", w.ReachedEnemies(), lose.MaxReachedEnemies)
+	} else if lose.LeakZone != nil {
+		status += fmt.Sprintf("  Leaks: %d", w.ReachedEnemies())
+	}
+	ebitenutil.DebugPrintAt(screen, status, 10, hudStatusY)
+}
+
+// drawBaseHP は本拠地の HP をバーで表示する。開始時の 3 割を下回ると赤で表示する
+func (g *Game) drawBaseHP(screen *ebiten.Image) {
+	base := g.world.Base()
+	hp := max(base.HP, 0)
+	maxHP := max(base.MaxHP(), hp, 1)
+	ebitenutil.DebugPrintAt(screen, fmt.Sprintf("Base: %d/%d", hp, base.MaxHP()), hudRightX, hudAreaY+36)
+
+	clr := baseHPColor
+	if hp*10 < base.MaxHP()*3 {
+		clr = baseHPLowColor
+	}
+	const y = hudAreaY + 54
+	vector.DrawFilledRect(screen, hudRightX, y, baseHPBarWidth*float32(hp)/float32(maxHP), baseHPBarHeight, clr, false)
+	vector.StrokeRect(screen, hudRightX, y, baseHPBarWidth, baseHPBarHeight, 1, color.White, false)
+}
+
+// bestWave は今のステージで片付けたウェーブの数の最高記録を、今のプレイも含めて返す
+func (g *Game) bestWave() int {
+	return max(g.bestWaves[g.world.Stage().ID], g.world.ClearedWaves())
+}
+
+// recordBestWave は今のプレイで片付けたウェーブの数を最高記録として残す。記録を更新した場合は true を返す
+// 早めに呼んだだけのウェーブで記録が伸びないよう、始まったウェーブではなく片付けたウェーブを数える
+func (g *Game) recordBestWave() bool {
+	id := g.world.Stage().ID
+	if g.world.ClearedWaves() <= g.bestWaves[id] {
+		return false
+	}
+	if g.bestWaves == nil {
+		g.bestWaves = map[string]int{}
+	}
+	g.bestWaves[id] = g.world.ClearedWaves()
+	return true
+}
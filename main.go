@@ -1,19 +1,73 @@
 package main
 
 import (
+	"flag"
+	"os"
+
 	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/pankona/generic-defence-game/sim"
 )
 
 const (
 	screenWidth  = 640
-	screenHeight = 640
+	screenHeight = sim.FieldHeight + hudAreaHeight + infoAreaHeight + marginBottom // フィールドの下に HUD と情報表示領域を並べる
 )
 
 func main() {
+	replayPath := flag.String("replay", "", "path to a replay file to play back")
+	stagePath := flag.String("stage", "", "path to a stage file to play in addition to the bundled stages")
+	flag.Parse()
+
 	ebiten.SetWindowSize(screenWidth, screenHeight)
 	ebiten.SetWindowTitle("Generic Shooting Game")
-	game := NewGame()
+
+	// 指定したステージファイルは、ビルドし直さなくてもステージ選択に並ぶ
+	var extraStages []sim.Stage
+	if *stagePath != "" {
+		stage, err := sim.LoadStageFile(*stagePath)
+		if err != nil {
+			panic(err)
+		}
+		extraStages = append(extraStages, stage)
+	}
+
+	var replay *sim.Replay
+	stageID := "sample"
+	if *replayPath != "" {
+		f, err := os.Open(*replayPath)
+		if err != nil {
+			panic(err)
+		}
+		replay, err = sim.LoadReplay(f)
+		f.Close()
+		if err != nil {
+			panic(err)
+		}
+		stageID = replay.StageID
+	}
+
+	stage, err := findStage(stageID, extraStages)
+	if err != nil {
+		panic(err)
+	}
+	game := NewGame(stage)
+	game.extraStages = extraStages
+	if replay != nil {
+		if err := game.startPlayback(replay); err != nil {
+			panic(err)
+		}
+	}
 	if err := ebiten.RunGame(game); err != nil {
 		panic(err)
 	}
 }
+
+// findStage は ID が一致するステージを、指定したステージファイル、同梱されているステージの順に探す
+func findStage(id string, extraStages []sim.Stage) (sim.Stage, error) {
+	for _, stage := range extraStages {
+		if stage.ID == id {
+			return stage, nil
+		}
+	}
+	return sim.LoadDefaultStage(id)
+}
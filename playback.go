@@ -0,0 +1,114 @@
+package main
+
+import (
+	"fmt"
+	"image/color"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/ebitenutil"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+	"github.com/pankona/generic-defence-game/sim"
+)
+
+// リプレイの再生速度の選択肢。キーの 1, 2, 3 に対応する
+var playbackSpeeds = []int{1, 2, 8}
+
+// 矢印キーでシークするティック数（5 秒分）
+const playbackSeekTicks = 5 * 60
+
+// タイムラインの表示位置
+const (
+	timelineX      = infoAreaX + sideMargin
+	timelineY      = infoAreaY + 60
+	timelineWidth  = screenWidth - timelineX*2
+	timelineHeight = 12
+)
+
+// リプレイの再生状態
+type playbackView struct {
+	playback *sim.Playback
+	speed    int
+	paused   bool
+}
+
+// startPlayback はリプレイの再生を開始する
+func (g *Game) startPlayback(replay *sim.Replay) error {
+	playback, err := sim.NewPlayback(g.world.Stage(), replay)
+	if err != nil {
+		return err
+	}
+	live := g.world
+	g.playback = &playbackView{playback: playback, speed: playbackSpeeds[0]}
+	g.world = playback.World()
+	g.selectUnits(nil)
+	g.setState(&replayingState{back: g.state, live: live})
+	return nil
+}
+
+func (g *Game) updatePlayback() {
+	v := g.playback
+	p := v.playback
+
+	if inpututil.IsKeyJustPressed(ebiten.KeySpace) {
+		v.paused = !v.paused
+	}
+	for i, key := range []ebiten.Key{ebiten.KeyDigit1, ebiten.KeyDigit2, ebiten.KeyDigit3} {
+		if inpututil.IsKeyJustPressed(key) {
+			v.speed = playbackSpeeds[i]
+		}
+	}
+	if inpututil.IsKeyJustPressed(ebiten.KeyArrowLeft) {
+		p.Seek(p.Tick() - playbackSeekTicks)
+	}
+	if inpututil.IsKeyJustPressed(ebiten.KeyArrowRight) {
+		p.Seek(p.Tick() + playbackSeekTicks)
+	}
+
+	// タイムライン上をクリック（ドラッグ）した位置にシークする
+	if x, y := g.pointer.x, g.pointer.y; g.pointer.held &&
+		x >= timelineX && x <= timelineX+timelineWidth && y >= timelineY && y <= timelineY+timelineHeight {
+		p.Seek(int((x - timelineX) / timelineWidth * float64(p.Ticks())))
+	} else if !v.paused {
+		for i := 0; i < v.speed; i++ {
+			p.Step()
+		}
+	}
+
+	g.world = p.World()
+}
+
+func (g *Game) drawPlayback(screen *ebiten.Image) {
+	v := g.playback
+	p := v.playback
+
+	status := fmt.Sprintf("REPLAY %dx", v.speed)
+	if v.paused {
+		status += " (paused)"
+	}
+	status += fmt.Sprintf("  %s / %s", formatTicks(p.Tick()), formatTicks(p.Ticks()))
+	ebitenutil.DebugPrintAt(screen, status, timelineX, infoAreaY+marginBottom)
+	ebitenutil.DebugPrintAt(screen, "Space: pause  1/2/3: 1x/2x/8x  Left/Right: -/+5s  Esc: exit", timelineX, infoAreaY+marginBottom+20)
+
+	// タイムライン本体と再生位置
+	progress := float32(0)
+	if p.Ticks() > 0 {
+		progress = float32(p.Tick()) / float32(p.Ticks())
+	}
+	vector.DrawFilledRect(screen, timelineX, timelineY, timelineWidth*progress, timelineHeight, color.RGBA{R: 80, G: 80, B: 160, A: 255}, false)
+	drawRectBorder(screen, timelineX, timelineY, timelineWidth, timelineHeight, color.White)
+
+	// 再生した位置までに始まったウェーブの開始位置に目印をつける
+	// 次のウェーブは早めに呼べるので、開始位置は再生してみるまでわからない
+	for i, start := range g.world.WaveStarts() {
+		x := timelineX + float32(start)/float32(max(p.Ticks(), 1))*timelineWidth
+		vector.StrokeLine(screen, x, timelineY-4, x, timelineY+timelineHeight+4, 1, color.RGBA{R: 255, G: 255, B: 0, A: 255}, false)
+		ebitenutil.DebugPrintAt(screen, fmt.Sprintf("W%d", i+1), int(x)+2, timelineY+timelineHeight+4)
+	}
+}
+
+// formatTicks はティック数を 60 TPS 前提の「分:秒」に変換する
+func formatTicks(ticks int) string {
+	seconds := ticks / 60
+	return fmt.Sprintf("%d:%02d", seconds/60, seconds%60)
+}
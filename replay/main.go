@@ -0,0 +1,55 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/pankona/generic-defence-game/sim"
+)
+
+// ゲーム終了時に出力されたリプレイを画面なしで再生し、結果を表示する
+// 同梱されていないステージのリプレイは、-stage でステージファイルを指定して再生する
+//
+//	go run ./replay [-stage stage.json] replay.json
+func main() {
+	stagePath := flag.String("stage", "", "path to the stage file the replay was recorded on (defaults to the bundled stage)")
+	flag.Parse()
+	if flag.NArg() != 1 {
+		log.Fatalf("usage: %s [-stage stage.json] <replay.json>", os.Args[0])
+	}
+
+	f, err := os.Open(flag.Arg(0))
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer f.Close()
+
+	replay, err := sim.LoadReplay(f)
+	if err != nil {
+		log.Fatal(err)
+	}
+	var stage sim.Stage
+	if *stagePath != "" {
+		stage, err = sim.LoadStageFile(*stagePath)
+	} else {
+		stage, err = sim.LoadDefaultStage(replay.StageID)
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+	w, err := sim.Simulate(stage, replay)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	fmt.Printf("stage: %s\n", stage.ID)
+	fmt.Printf("seed: %d\n", replay.Seed)
+	fmt.Printf("ticks: %d\n", w.Tick())
+	fmt.Printf("status: %s\n", w.Status())
+	fmt.Printf("wave: %d\n", w.Wave())
+	fmt.Printf("cleared: %d\n", w.ClearedWaves())
+	fmt.Printf("money: %d\n", w.Money())
+	fmt.Printf("base hp: %d\n", w.Base().HP)
+}
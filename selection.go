@@ -0,0 +1,325 @@
+package main
+
+import (
+	"fmt"
+	"image/color"
+	"math"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/ebitenutil"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+	"github.com/pankona/generic-defence-game/sim"
+)
+
+// これより短いドラッグは範囲選択ではなくクリックとして扱う
+const boxSelectThreshold = 5
+
+// 選択中のユニットを囲む枠の色
+var selectionColor = color.RGBA{R: 0, G: 255, B: 0, A: 255}
+
+// selectUnits は ids のユニットを選択し、情報パネルに表示する
+func (g *Game) selectUnits(ids []sim.EntityID) {
+	g.selection = ids
+	g.unitInfoPanel = nil
+	g.pendingOrder = ""
+	if players := g.selectedPlayers(); len(players) > 0 && len(players) == len(ids) {
+		// プレイヤーユニットだけを選択している場合は指示のボタンを表示する
+		g.unitInfoPanel = NewUnitInfoPanel(players[0])
+		g.unitInfoPanel.SetButtons([]*Button{
+			{
+				order:  sim.OrderHold,
+				text:   []string{"Hold", "Stay here"},
+				x:      infoAreaX + sideMargin + 200,
+				y:      infoAreaY + 5,
+				width:  100,
+				height: infoAreaHeight - 10,
+			},
+			{
+				order:  sim.OrderPatrol,
+				text:   []string{"Patrol", "Click a point"},
+				x:      infoAreaX + sideMargin + 300 + 5,
+				y:      infoAreaY + 5,
+				width:  100,
+				height: infoAreaHeight - 10,
+			},
+			targetingButton(infoAreaX + sideMargin + 400 + 10),
+		})
+		return
+	}
+	if len(ids) != 1 {
+		return
+	}
+	unit, ok := g.world.Entity(ids[0]).(Clickable)
+	if !ok {
+		return
+	}
+	g.unitInfoPanel = NewUnitInfoPanel(unit)
+	switch unit.(type) {
+	case *sim.Tower:
+		g.unitInfoPanel.SetButtons([]*Button{
+			targetingButton(infoAreaX + sideMargin + 200),
+		})
+	case *sim.Base:
+		g.unitInfoPanel.SetButtons([]*Button{
+			{
+				command: sim.CommandRecoverHP,
+				text:    []string{"Recover HP", "+10HP / $10"},
+				x:       infoAreaX + sideMargin + 100,
+				y:       infoAreaY + 5,
+				width:   100,
+				height:  infoAreaHeight - 10,
+			},
+			{
+				command: sim.CommandTrainUnit,
+				text:    []string{"Train Unit", "$100"},
+				x:       infoAreaX + sideMargin + 200 + 5,
+				y:       infoAreaY + 5,
+				width:   100,
+				height:  infoAreaHeight - 10,
+			},
+			{
+				command: sim.CommandHastenRespawn,
+				text:    []string{"Respawn Now", "$5 / sec left"},
+				x:       infoAreaX + sideMargin + 300 + 10,
+				y:       infoAreaY + 5,
+				width:   100,
+				height:  infoAreaHeight - 10,
+			},
+			{
+				action: (*Game).togglePlacingTower,
+				active: func(g *Game) bool { return g.placingTower },
+				text:   []string{"Build Tower", fmt.Sprintf("$%d", sim.TowerCost)},
+				x:      infoAreaX + sideMargin + 400 + 15,
+				y:      infoAreaY + 5,
+				width:  100,
+				height: infoAreaHeight - 10,
+			},
+		})
+	}
+}
+
+// targetingButton は攻撃対象の選び方を切り替えるボタンを返す
+func targetingButton(x float64) *Button {
+	return &Button{
+		order:  sim.OrderTargeting,
+		text:   []string{"Target"},
+		label:  (*Game).selectedTargeting,
+		x:      x,
+		y:      infoAreaY + 5,
+		width:  100,
+		height: infoAreaHeight - 10,
+	}
+}
+
+// targeter は攻撃対象の選び方を持つユニット
+type targeter interface {
+	Targeting() string
+}
+
+// selectedTargeting は最初に選択したユニットの攻撃対象の選び方を返す。該当するユニットがなければ空文字列を返す
+func (g *Game) selectedTargeting() string {
+	if len(g.selection) == 0 {
+		return ""
+	}
+	if unit, ok := g.world.Entity(g.selection[0]).(targeter); ok {
+		return unit.Targeting()
+	}
+	return ""
+}
+
+// toggleSelectedPlayer はプレイヤーユニットを選択に加える。すでに選択されていれば選択から外す
+// プレイヤーユニット以外が選択されていた場合は、その選択を解除する
+func (g *Game) toggleSelectedPlayer(id sim.EntityID) {
+	var ids []sim.EntityID
+	found := false
+	for _, player := range g.selectedPlayers() {
+		if player.ID() == id {
+			found = true
+			continue
+		}
+		ids = append(ids, player.ID())
+	}
+	if !found {
+		ids = append(ids, id)
+	}
+	g.selectUnits(ids)
+}
+
+// selectedPlayers は選択中のユニットのうち、ワールドに存在するプレイヤーユニットを返す
+func (g *Game) selectedPlayers() []*sim.Player {
+	var players []*sim.Player
+	for _, id := range g.selection {
+		if player, ok := g.world.Entity(id).(*sim.Player); ok {
+			players = append(players, player)
+		}
+	}
+	return players
+}
+
+// selectedPlayerIDs は選択中のプレイヤーユニットの ID を返す
+func (g *Game) selectedPlayerIDs() []sim.EntityID {
+	var ids []sim.EntityID
+	for _, player := range g.selectedPlayers() {
+		ids = append(ids, player.ID())
+	}
+	return ids
+}
+
+// nextTargeting は選択中のユニットに次に設定する攻撃対象の選び方を返す
+// 最初に選択したユニットの選び方を基準に、登録順で次のものを選ぶ
+func (g *Game) nextTargeting() string {
+	names := sim.Targetings()
+	current := g.selectedTargeting()
+	for i, name := range names {
+		if name == current {
+			return names[(i+1)%len(names)]
+		}
+	}
+	return names[0]
+}
+
+// clickedUnit は押された位置にあるユニットの ID を返す。なければ 0 を返す
+func (g *Game) clickedUnit() sim.EntityID {
+	enemies := g.world.Enemies()
+	for i := range enemies {
+		if g.pointer.pressedOn(&enemies[i]) {
+			return enemies[i].ID()
+		}
+	}
+	players := g.world.Players()
+	for i := range players {
+		if g.pointer.pressedOn(&players[i]) {
+			return players[i].ID()
+		}
+	}
+	towers := g.world.Towers()
+	for i := range towers {
+		if g.pointer.pressedOn(&towers[i]) {
+			return towers[i].ID()
+		}
+	}
+	if base := g.world.Base(); g.pointer.pressedOn(base) {
+		return base.ID()
+	}
+	return 0
+}
+
+// updateSelection はクリックによるユニットの選択と、ドラッグによる範囲選択を処理する
+// 選択に関係しない短いクリックの場合は、選択中のユニットへの移動指示を返す
+func (g *Game) updateSelection() []sim.Order {
+	shift := ebiten.IsKeyPressed(ebiten.KeyShift)
+
+	if g.pointer.pressed && !g.pointer.consumed {
+		if id := g.clickedUnit(); id != 0 {
+			if _, ok := g.world.Entity(id).(*sim.Player); ok && shift {
+				g.toggleSelectedPlayer(id)
+			} else {
+				g.selectUnits([]sim.EntityID{id})
+			}
+			g.pointer.consume()
+			return nil
+		}
+		g.boxSelecting = true
+	}
+
+	if !g.pointer.released || !g.boxSelecting {
+		return nil
+	}
+	g.boxSelecting = false
+
+	if !g.pointer.dragged(boxSelectThreshold) {
+		// 地面をクリックした場合は選択中のユニットに指示を出す
+		// Shift を押しながらクリックした場合は経由地として追加する
+		ids := g.selectedPlayerIDs()
+		if len(ids) == 0 {
+			return nil
+		}
+		kind := g.pendingOrder
+		if kind == "" {
+			kind = sim.OrderMove
+		}
+		g.pendingOrder = ""
+		return []sim.Order{{Kind: kind, Units: ids, Target: sim.Point{X: g.pointer.x, Y: g.pointer.y}, Queue: shift}}
+	}
+
+	// 囲んだ範囲に中心があるプレイヤーユニットを選択する
+	var ids []sim.EntityID
+	if shift {
+		ids = g.selectedPlayerIDs()
+	}
+	x0, y0, x1, y1 := g.pointer.dragRect()
+	players := g.world.Players()
+	for i := range players {
+		player := &players[i]
+		cx, cy := player.GetX()+player.GetRadius(), player.GetY()+player.GetRadius()
+		if cx < x0 || cx > x1 || cy < y0 || cy > y1 || containsID(ids, player.ID()) {
+			continue
+		}
+		ids = append(ids, player.ID())
+	}
+	g.selectUnits(ids)
+	return nil
+}
+
+func containsID(ids []sim.EntityID, id sim.EntityID) bool {
+	for _, v := range ids {
+		if v == id {
+			return true
+		}
+	}
+	return false
+}
+
+// 選択中のユニットの経由地を結ぶ線の色
+var waypointColor = color.RGBA{R: 0, G: 160, B: 0, A: 255}
+
+// drawSelection は選択中のユニットを枠で囲み、範囲選択中であればその範囲を描画する
+func (g *Game) drawSelection(screen *ebiten.Image) {
+	// 選択中のユニットがこれから通る経由地を線で結ぶ。巡回中は最後の経由地から最初の経由地にも結ぶ
+	for _, player := range g.selectedPlayers() {
+		waypoints := player.Waypoints()
+		x, y := player.GetX()+player.GetRadius(), player.GetY()+player.GetRadius()
+		for _, wp := range waypoints {
+			vector.StrokeLine(screen, float32(x), float32(y), float32(wp.X), float32(wp.Y), 1, waypointColor, false)
+			x, y = wp.X, wp.Y
+		}
+		if player.OrderKind() == sim.OrderPatrol && len(waypoints) > 1 {
+			vector.StrokeLine(screen, float32(x), float32(y), float32(waypoints[0].X), float32(waypoints[0].Y), 1, waypointColor, false)
+		}
+	}
+
+	for _, id := range g.selection {
+		if entity := g.world.Entity(id); entity != nil {
+			r := entity.GetRadius()
+			x, y := int(entity.GetX())-2, int(entity.GetY())-2
+			drawRectBorder(screen, x, y, int(r*2)+4, int(r*2)+4, selectionColor)
+		}
+	}
+
+	if g.boxSelecting && g.pointer.dragged(boxSelectThreshold) {
+		x0, y0, x1, y1 := g.pointer.dragRect()
+		drawRectBorder(screen, int(x0), int(y0), int(x1-x0), int(y1-y0), selectionColor)
+	}
+}
+
+// drawGroupInfo は複数のプレイヤーユニットを選択しているときに、その概要を表示する
+func drawGroupInfo(screen *ebiten.Image, players []*sim.Player) {
+	damage, hp, maxHP := 0, 0, 0
+	minRange, maxRange := math.Inf(1), 0.0
+	for _, player := range players {
+		hp += player.HP
+		maxHP += player.MaxHP()
+		weapon := player.Weapon()
+		damage += weapon.Damage
+		minRange = math.Min(minRange, weapon.Range)
+		maxRange = math.Max(maxRange, weapon.Range)
+	}
+	ebitenutil.DebugPrintAt(screen, fmt.Sprintf("%d Players", len(players)), infoAreaX+sideMargin, infoAreaY+marginBottom)
+	ebitenutil.DebugPrintAt(screen, fmt.Sprintf("HP: %d/%d", hp, maxHP), infoAreaX+sideMargin, infoAreaY+marginBottom+20)
+	ebitenutil.DebugPrintAt(screen, fmt.Sprintf("Total ATK: %d", damage), infoAreaX+sideMargin, infoAreaY+marginBottom+40)
+	rng := fmt.Sprintf("RNG: %d", int(minRange))
+	if maxRange != minRange {
+		rng = fmt.Sprintf("RNG: %d-%d", int(minRange), int(maxRange))
+	}
+	ebitenutil.DebugPrintAt(screen, rng, infoAreaX+sideMargin, infoAreaY+marginBottom+60)
+}
@@ -0,0 +1,175 @@
+package sim
+
+import (
+	"bytes"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"image/color"
+	"io"
+	"strconv"
+)
+
+// ArchetypeFormatVersion は読み込み可能な敵の種類ファイルのフォーマットのバージョン
+const ArchetypeFormatVersion = 1
+
+// 敵の振る舞いを変えるフラグ
+const (
+	FlagIgnoreWalls = "ignore_walls" // 壁の効果を受けず、block の壁も通り抜ける
+)
+
+// 敵が攻撃する相手の選び方
+const (
+	EnemyTargetBase = "base" // 本拠地だけを狙う（既定）
+	EnemyTargetUnit = "unit" // AggroRange 内にプレイヤーユニットがいれば、最も近いユニットを狙う
+)
+
+// EnemyArchetype は敵の種類ごとの性能を表す
+type EnemyArchetype struct {
+	ID     string   `json:"id"`              // ステージファイルから参照する ID
+	Name   string   `json:"name"`            // 表示用の名前
+	Speed  float64  `json:"speed"`           // 移動速度
+	HP     int      `json:"hp"`              // ヒットポイント
+	Armor  int      `json:"armor,omitempty"` // 防御力。受けるダメージがこの分だけ減る
+	Reward int      `json:"reward"`          // 倒したときに得られるお金
+	Weapon Weapon   `json:"weapon"`          // 装備している武器
+	Size   float64  `json:"size"`            // 一辺の長さ
+	Color  string   `json:"color"`           // 描画色。"#rrggbb" または "#rrggbbaa"
+	Flags  []string `json:"flags,omitempty"`
+
+	Target     string  `json:"target,omitempty"`      // 攻撃する相手の選び方。省略時は base
+	AggroRange float64 `json:"aggro_range,omitempty"` // unit: プレイヤーユニットに狙いを変える距離
+
+	rgba color.RGBA
+}
+
+// RGBA は描画色を返す
+func (a *EnemyArchetype) RGBA() color.RGBA {
+	return a.rgba
+}
+
+// HasFlag は指定したフラグが設定されているかを返す
+func (a *EnemyArchetype) HasFlag(flag string) bool {
+	for _, f := range a.Flags {
+		if f == flag {
+			return true
+		}
+	}
+	return false
+}
+
+func (a *EnemyArchetype) validate() error {
+	if a.ID == "" {
+		return fmt.Errorf("archetype id is empty")
+	}
+	if a.Speed < 0 {
+		return fmt.Errorf("archetype %q: speed must not be negative: %g", a.ID, a.Speed)
+	}
+	if a.HP <= 0 {
+		return fmt.Errorf("archetype %q: hp must be positive: %d", a.ID, a.HP)
+	}
+	if a.Armor < 0 {
+		return fmt.Errorf("archetype %q: armor must not be negative: %d", a.ID, a.Armor)
+	}
+	if a.Reward < 0 {
+		return fmt.Errorf("archetype %q: reward must not be negative: %d", a.ID, a.Reward)
+	}
+	if err := a.Weapon.validate(); err != nil {
+		return fmt.Errorf("archetype %q: weapon: %w", a.ID, err)
+	}
+	if a.Size <= 0 {
+		return fmt.Errorf("archetype %q: size must be positive: %g", a.ID, a.Size)
+	}
+	switch a.Target {
+	case "", EnemyTargetBase:
+	case EnemyTargetUnit:
+		if a.AggroRange <= 0 {
+			return fmt.Errorf("archetype %q: aggro range must be positive: %g", a.ID, a.AggroRange)
+		}
+	default:
+		return fmt.Errorf("archetype %q: unknown target %q", a.ID, a.Target)
+	}
+	for _, flag := range a.Flags {
+		switch flag {
+		case FlagIgnoreWalls:
+		default:
+			return fmt.Errorf("archetype %q: unknown flag %q", a.ID, flag)
+		}
+	}
+	rgba, err := parseHexColor(a.Color)
+	if err != nil {
+		return fmt.Errorf("archetype %q: %w", a.ID, err)
+	}
+	a.rgba = rgba
+	return nil
+}
+
+// parseHexColor は "#rrggbb" または "#rrggbbaa" 形式の色を解釈する
+func parseHexColor(s string) (color.RGBA, error) {
+	if len(s) != 7 && len(s) != 9 || s[0] != '#' {
+		return color.RGBA{}, fmt.Errorf("invalid color %q", s)
+	}
+	v, err := strconv.ParseUint(s[1:], 16, 32)
+	if err != nil {
+		return color.RGBA{}, fmt.Errorf("invalid color %q", s)
+	}
+	if len(s) == 7 {
+		v = v<<8 | 0xff
+	}
+	return color.RGBA{R: uint8(v >> 24), G: uint8(v >> 16), B: uint8(v >> 8), A: uint8(v)}, nil
+}
+
+// ArchetypeRegistry は ID から敵の種類を引くための一覧
+type ArchetypeRegistry struct {
+	archetypes map[string]*EnemyArchetype
+}
+
+// LoadArchetypes は JSON 形式の敵の種類の一覧を読み込み、内容を検証する
+func LoadArchetypes(r io.Reader) (*ArchetypeRegistry, error) {
+	var file struct {
+		Version    int               `json:"version"`
+		Archetypes []*EnemyArchetype `json:"archetypes"`
+	}
+	dec := json.NewDecoder(r)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&file); err != nil {
+		return nil, fmt.Errorf("failed to decode archetypes: %w", err)
+	}
+	if file.Version != ArchetypeFormatVersion {
+		return nil, fmt.Errorf("unsupported archetype format version %d (want %d)", file.Version, ArchetypeFormatVersion)
+	}
+
+	registry := &ArchetypeRegistry{archetypes: map[string]*EnemyArchetype{}}
+	for _, archetype := range file.Archetypes {
+		if err := archetype.validate(); err != nil {
+			return nil, err
+		}
+		if _, ok := registry.archetypes[archetype.ID]; ok {
+			return nil, fmt.Errorf("duplicate archetype id %q", archetype.ID)
+		}
+		registry.archetypes[archetype.ID] = archetype
+	}
+	return registry, nil
+}
+
+// Lookup は ID に対応する敵の種類を返す
+func (r *ArchetypeRegistry) Lookup(id string) (*EnemyArchetype, bool) {
+	archetype, ok := r.archetypes[id]
+	return archetype, ok
+}
+
+// ゲームに同梱する敵の種類の一覧
+//
+//go:embed archetypes.json
+var archetypesJSON []byte
+
+// archetypes はステージファイルから参照される敵の種類の一覧
+var archetypes = mustLoadDefaultArchetypes()
+
+func mustLoadDefaultArchetypes() *ArchetypeRegistry {
+	registry, err := LoadArchetypes(bytes.NewReader(archetypesJSON))
+	if err != nil {
+		panic(fmt.Sprintf("archetypes.json: %v", err))
+	}
+	return registry
+}
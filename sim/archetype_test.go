@@ -0,0 +1,55 @@
+package sim
+
+import (
+	"strings"
+	"testing"
+)
+
+// validArchetype は検証を通る敵の種類の JSON。テストでは一部を置き換えて使う
+const validArchetype = `{
+	"id": "test",
+	"speed": 1,
+	"hp": 1,
+	"reward": 1,
+	"weapon": {"damage": 1, "range": 10, "cooldown": 10, "projectile_speed": 1, "projectile": "homing"},
+	"size": 8,
+	"color": "#ffffff"
+}`
+
+func loadArchetypes(archetypes ...string) (*ArchetypeRegistry, error) {
+	return LoadArchetypes(strings.NewReader(`{"version": 1, "archetypes": [` + strings.Join(archetypes, ",") + `]}`))
+}
+
+func TestLoadArchetypesErrors(t *testing.T) {
+	for _, tt := range []struct {
+		name, old, new, want string
+	}{
+		{"empty id", `"id": "test"`, `"id": ""`, "archetype id is empty"},
+		{"negative speed", `"speed": 1`, `"speed": -1`, "speed must not be negative"},
+		{"hp", `"hp": 1`, `"hp": 0`, "hp must be positive"},
+		{"negative reward", `"reward": 1`, `"reward": -1`, "reward must not be negative"},
+		{"negative armor", `"reward": 1`, `"reward": 1, "armor": -1`, "armor must not be negative"},
+		{"size", `"size": 8`, `"size": 0`, "size must be positive"},
+		{"color", `"#ffffff"`, `"white"`, `invalid color "white"`},
+		{"unknown flag", `"size": 8`, `"size": 8, "flags": ["fly"]`, `unknown flag "fly"`},
+		{"unknown target", `"size": 8`, `"size": 8, "target": "tower"`, `unknown target "tower"`},
+		{"aggro range", `"size": 8`, `"size": 8, "target": "unit"`, "aggro range must be positive"},
+		{"unknown projectile", `"homing"`, `"boomerang"`, "weapon"},
+		{"unknown effect", `"homing"`, `"homing", "effects": ["freeze"]`, `weapon: unknown effect "freeze"`},
+		{"unknown field", `"size": 8`, `"size": 8, "wings": 2`, "wings"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := loadArchetypes(strings.Replace(validArchetype, tt.old, tt.new, 1))
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("LoadArchetypes() = %v, want an error containing %q", err, tt.want)
+			}
+		})
+	}
+
+	if _, err := loadArchetypes(validArchetype, validArchetype); err == nil || !strings.Contains(err.Error(), "duplicate") {
+		t.Errorf("LoadArchetypes() = %v, want a duplicate id error", err)
+	}
+	if _, err := LoadArchetypes(strings.NewReader(`{"version": 2, "archetypes": []}`)); err == nil {
+		t.Error("LoadArchetypes() accepted an unsupported version")
+	}
+}
@@ -1,38 +1,29 @@
-package main
+package sim
 
 import (
-	"image/color"
 	"math"
-
-	"github.com/hajimehoshi/ebiten/v2"
 )
 
 // Base (本拠地)を表す構造体
 type Base struct {
-	x, y float64
-	HP   int
-	img  *ebiten.Image
+	id    EntityID
+	x, y  float64
+	HP    int
+	maxHP int // 開始時の HP。回復すると上回ることがある
+
+	effects statusEffects
 }
 
 // Baseの初期化
-func NewBase() *Base {
-	img := ebiten.NewImage(32, 32) // 本拠地の画像サイズ
-	img.Fill(color.RGBA{R: 255, G: 255, B: 0, A: 255})
+func NewBase(config BaseConfig) *Base {
 	return &Base{
-		x:   600, // 位置の調整
-		y:   440, // 位置の調整
-		HP:  20,  // 本拠地のヒットポイント
-		img: img,
+		x:     config.X,
+		y:     config.Y,
+		HP:    config.HP, // 本拠地のヒットポイント
+		maxHP: config.HP,
 	}
 }
 
-// Baseの描画
-func (b *Base) Draw(screen *ebiten.Image) {
-	op := &ebiten.DrawImageOptions{}
-	op.GeoM.Translate(b.x, b.y)
-	screen.DrawImage(b.img, op)
-}
-
 func (b *Base) IsHit(bulletX, bulletY float64) bool {
 	const enemyRadius, bulletRadius = 8, 2 // 敵と弾の半径。適切なサイズに調整してください
 
@@ -45,6 +36,10 @@ func (b *Base) IsHit(bulletX, bulletY float64) bool {
 	return distance < (enemyRadius + bulletRadius)
 }
 
+func (b *Base) ID() EntityID {
+	return b.id
+}
+
 func (b *Base) GetX() float64 {
 	return b.x
 }
@@ -66,33 +61,35 @@ func (b *Base) GetSize() (width, height int) {
 	return int(radius * 2), int(radius * 2)
 }
 
-func (b *Base) recoverHP(g *Game) {
+// MaxHP は本拠地の開始時の HP を返す
+func (b *Base) MaxHP() int {
+	return b.maxHP
+}
+
+// Effects は本拠地が受けている状態効果の名前を受けた順に返す
+func (b *Base) Effects() []EffectID {
+	return b.effects.ids()
+}
+
+func (b *Base) statusEffects() *statusEffects {
+	return &b.effects
+}
+
+func (b *Base) recoverHP(w *World) {
 	const cost = 10
 	const recovery = 10
 
-	if g.money >= cost {
+	if w.money >= cost {
 		b.HP += recovery
-		g.money -= cost
+		w.money -= cost
 	}
 }
 
-func (b *Base) trainUnit(g *Game) {
+func (b *Base) trainUnit(w *World) {
 	const cost = 100
 
-	if g.money >= cost {
-		g.players = append(g.players, NewPlayer())
-		g.money -= cost
+	if w.money >= cost {
+		w.addPlayer(NewPlayer())
+		w.money -= cost
 	}
 }
-
-type RecoverButton struct {
-	x, y, width, height int
-}
-
-func (r *RecoverButton) Bounds() (x, y, width, height int) {
-	return r.x, r.y, r.width, r.height
-}
-
-func (r *RecoverButton) Clicked(g *Game) {
-	g.base.recoverHP(g)
-}
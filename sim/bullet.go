@@ -0,0 +1,120 @@
+package sim
+
+import (
+	"math"
+)
+
+// 弾の当たり判定に使う半径
+const bulletRadius = 2
+
+type Bullet struct {
+	x, y     float64
+	vx, vy   float64 // 1 フレームあたりの移動量
+	active   bool
+	targetID EntityID
+	hostile  bool   // 敵が発射した弾かどうか
+	weapon   Weapon // 弾を発射した武器
+
+	// 命中済みの相手（貫通弾が同じ相手に何度も当たらないようにする）
+	hits []EntityID
+}
+
+func NewBullet(x, y float64, target Entity, weapon *Weapon, hostile bool) Bullet {
+	b := Bullet{
+		x:        x,
+		y:        y,
+		active:   true,
+		targetID: target.ID(),
+		hostile:  hostile,
+		weapon:   *weapon,
+	}
+	// 発射した時点のターゲットの位置に向けて飛ばす
+	b.aimAt(target)
+	return b
+}
+
+// aimAt は弾の進行方向をターゲットの中心に向ける
+func (b *Bullet) aimAt(target Entity) {
+	dx := target.GetX() + target.GetRadius() - b.x
+	dy := target.GetY() + target.GetRadius() - b.y
+	dist := math.Sqrt(dx*dx + dy*dy)
+
+	if dist != 0 {
+		dx /= dist
+		dy /= dist
+	}
+
+	b.vx = dx * b.weapon.ProjectileSpeed
+	b.vy = dy * b.weapon.ProjectileSpeed
+}
+
+func (b *Bullet) Update(w *World) {
+	// 弾の動きのロジック
+	// 直進する弾は発射時の向きのまま進み、それ以外はターゲットを追尾する
+	if !b.weapon.Projectile.straight() {
+		target := w.Entity(b.targetID)
+		if target == nil {
+			target = b.onTargetLost(w)
+		}
+		if !b.active {
+			return
+		}
+		if target != nil {
+			b.aimAt(target)
+		}
+	}
+
+	b.x += b.vx
+	b.y += b.vy
+
+	// 画面外に出たら弾を消す
+	if !inField(b.x, b.y) {
+		b.active = false
+	}
+}
+
+// onTargetLost はターゲットが倒されたときに、武器の設定に従って弾の振る舞いを決める
+// 新しいターゲットが見つかった場合はそれを返す
+func (b *Bullet) onTargetLost(w *World) Entity {
+	switch b.weapon.OnTargetLost {
+	case TargetLostVanish:
+		b.active = false
+	case TargetLostRetarget:
+		var target Entity
+		if b.hostile {
+			target = w.base
+		} else if enemy := w.nearestEnemyTo(b.x, b.y, b.weapon.Range, b.hasHit); enemy != nil {
+			target = enemy
+		}
+		if target != nil {
+			b.targetID = target.ID()
+			return target
+		}
+	}
+	// 直前の向きのまま直進する
+	b.targetID = 0
+	return nil
+}
+
+// hasHit はこの弾がすでに target に命中しているかを返す
+func (b *Bullet) hasHit(target Entity) bool {
+	for _, id := range b.hits {
+		if id == target.ID() {
+			return true
+		}
+	}
+	return false
+}
+
+func (b *Bullet) GetX() float64 {
+	return b.x
+}
+
+func (b *Bullet) GetY() float64 {
+	return b.y
+}
+
+// Kind は弾の種類を返す
+func (b *Bullet) Kind() ProjectileKind {
+	return b.weapon.Projectile
+}
@@ -0,0 +1,38 @@
+package sim
+
+import "testing"
+
+// 追尾中のターゲットが倒されたときの弾の振る舞いは on_target_lost に従う
+func TestTargetLostPolicies(t *testing.T) {
+	for _, tt := range []struct {
+		policy     TargetLostPolicy
+		wantVanish bool // ターゲットが倒された直後に消えるか
+		wantDamage int  // 近くにいる別の敵が受けるダメージ
+	}{
+		{TargetLostContinue, false, 0},
+		{TargetLostRetarget, false, 1},
+		{TargetLostVanish, true, 0},
+	} {
+		t.Run(string(tt.policy), func(t *testing.T) {
+			w := newCombatWorld()
+			// 弾は真上から a を狙う。b は弾の進路から外れた位置にいる
+			targets := placeTargets(w, false, []Point{{300, 300}, {360, 300}})
+			weapon := Weapon{Damage: 1, Range: 200, Cooldown: 1, ProjectileSpeed: 8, Projectile: ProjectileHoming, OnTargetLost: tt.policy}
+			w.playerBullets = append(w.playerBullets, weapon.fire(308, 200, w.Entity(targets[0]), false))
+			w.Step(Input{})
+			w.Damage(w.Entity(targets[0]), 100)
+			w.Step(Input{})
+			if vanished := len(w.playerBullets) == 0; vanished != tt.wantVanish {
+				t.Fatalf("bullet vanished = %t, want %t", vanished, tt.wantVanish)
+			}
+
+			for len(w.playerBullets) > 0 && w.Tick() < 1000 {
+				w.Step(Input{})
+			}
+			other := w.Entity(targets[1]).(*Enemy)
+			if got := other.archetype.HP - other.HP; got != tt.wantDamage {
+				t.Errorf("damage to the other enemy = %d, want %d", got, tt.wantDamage)
+			}
+		})
+	}
+}
@@ -0,0 +1,182 @@
+package sim
+
+import "math"
+
+// hit は武器の攻撃が target に命中したときに、ダメージと武器の状態効果を与える
+func (w *World) hit(target Entity, weapon *Weapon) {
+	w.Damage(target, weapon.Damage)
+	applyEffects(target, weapon.Effects)
+}
+
+// Damage は target に防御力と状態効果を反映したダメージを与える
+func (w *World) Damage(target Entity, amount int) {
+	switch t := target.(type) {
+	case *Enemy:
+		w.damageEnemy(t, t.effects.modifyDamage(amount, t.archetype.Armor))
+	case *Player:
+		t.HP -= t.effects.modifyDamage(amount, 0)
+	case *Base:
+		t.HP -= t.effects.modifyDamage(amount, 0)
+		if t.HP <= 0 {
+			w.status = Lost
+		}
+	}
+}
+
+// damageEnemy は敵にダメージを与え、倒した場合は報酬を得る
+func (w *World) damageEnemy(enemy *Enemy, damage int) {
+	if !enemy.active {
+		return
+	}
+	enemy.HP -= damage
+	if enemy.HP <= 0 {
+		enemy.active = false
+		w.money += enemy.archetype.Reward
+		w.kills++
+	}
+}
+
+// bulletHit は弾が target に命中したときの処理を弾の種類ごとに行う
+// プレイヤーの弾は敵に、敵の弾は本拠地とプレイヤーユニットに当たる
+func (w *World) bulletHit(bullet *Bullet, target Entity) {
+	weapon := &bullet.weapon
+	bullet.hits = append(bullet.hits, target.ID())
+
+	switch weapon.Projectile {
+	case ProjectilePiercing:
+		// 命中できる数に達するまでは消えずに進み続ける
+		w.hit(target, weapon)
+		if len(bullet.hits) >= weapon.Pierce {
+			bullet.active = false
+		}
+	case ProjectileSplash:
+		// 命中した地点の周囲の相手すべてにダメージを与える
+		for _, t := range w.splashTargets(bullet) {
+			w.hit(t, weapon)
+		}
+		bullet.active = false
+	case ProjectileChain:
+		// 命中した相手から、まだ当たっていない最も近い相手へ順に連鎖させる
+		w.hit(target, weapon)
+		last := target
+		for n := 0; n < weapon.ChainCount; n++ {
+			next := w.nearestTargetTo(bullet, last.GetX(), last.GetY(), weapon.ChainRange)
+			if next == nil {
+				break
+			}
+			bullet.hits = append(bullet.hits, next.ID())
+			w.hit(next, weapon)
+			last = next
+		}
+		bullet.active = false
+	default:
+		w.hit(target, weapon)
+		bullet.active = false
+	}
+}
+
+// splashTargets は弾が命中した地点から爆風の届く相手を返す
+func (w *World) splashTargets(bullet *Bullet) []Entity {
+	radius := bullet.weapon.SplashRadius
+	inSplash := func(t Entity) bool {
+		dx := t.GetX() + t.GetRadius() - bullet.x
+		dy := t.GetY() + t.GetRadius() - bullet.y
+		return math.Sqrt(dx*dx+dy*dy) <= radius+t.GetRadius()
+	}
+
+	var targets []Entity
+	if bullet.hostile {
+		for _, t := range w.friendlies() {
+			if inSplash(t) {
+				targets = append(targets, t)
+			}
+		}
+		return targets
+	}
+	for _, i := range w.grid.query(nil, bullet.x, bullet.y, radius+w.grid.maxRadius) {
+		if e := &w.enemies[i]; inSplash(e) {
+			targets = append(targets, e)
+		}
+	}
+	return targets
+}
+
+// nearestTargetTo は (x, y) から maxDistance 以内にいて弾がまだ当たっていない相手のうち、最も近いものを返す
+func (w *World) nearestTargetTo(bullet *Bullet, x, y, maxDistance float64) Entity {
+	if !bullet.hostile {
+		if enemy := w.nearestEnemyTo(x, y, maxDistance, bullet.hasHit); enemy != nil {
+			return enemy
+		}
+		return nil
+	}
+	var nearest Entity
+	nearestDistance := maxDistance
+	for _, t := range w.friendlies() {
+		if bullet.hasHit(t) {
+			continue
+		}
+		if distance := math.Hypot(t.GetX()-x, t.GetY()-y); distance <= nearestDistance {
+			nearest = t
+			nearestDistance = distance
+		}
+	}
+	return nearest
+}
+
+// friendlies は敵の弾が当たる相手として、本拠地と倒されていないプレイヤーユニットを返す
+func (w *World) friendlies() []Entity {
+	targets := []Entity{w.base}
+	for i := range w.players {
+		if w.players[i].HP > 0 {
+			targets = append(targets, &w.players[i])
+		}
+	}
+	return targets
+}
+
+// nearestEnemyTo は (x, y) から maxDistance 以内にいる生存中の敵のうち、最も近いものを返す
+// exclude が true を返す敵は対象外とする
+func (w *World) nearestEnemyTo(x, y, maxDistance float64, exclude func(Entity) bool) *Enemy {
+	var nearest *Enemy
+	nearestDistance := maxDistance
+	// 索引は敵の中心座標で作っているので、左上の座標で比べる分だけ広く検索する
+	for _, i := range w.grid.query(nil, x, y, maxDistance+2*w.grid.maxRadius) {
+		e := &w.enemies[i]
+		if !e.active || exclude(e) {
+			continue
+		}
+		dx := e.x - x
+		dy := e.y - y
+		distance := math.Sqrt(dx*dx + dy*dy)
+		if distance <= nearestDistance {
+			nearest = e
+			nearestDistance = distance
+		}
+	}
+	return nearest
+}
+
+// enemyTarget は敵が狙う相手を返す。敵の種類の設定に従って、本拠地か最も近いプレイヤーユニットを選ぶ
+func (w *World) enemyTarget(enemy *Enemy) Entity {
+	if enemy.archetype.Target == EnemyTargetUnit {
+		if player := w.nearestPlayerTo(enemy.x, enemy.y, enemy.archetype.AggroRange); player != nil {
+			return player
+		}
+	}
+	return w.base
+}
+
+// nearestPlayerTo は (x, y) から maxDistance 以内にいるプレイヤーユニットのうち最も近いものを返す
+func (w *World) nearestPlayerTo(x, y, maxDistance float64) *Player {
+	var nearest *Player
+	nearestDistance := maxDistance
+	for i := range w.players {
+		p := &w.players[i]
+		distance := math.Hypot(p.x-x, p.y-y)
+		if distance <= nearestDistance {
+			nearest = p
+			nearestDistance = distance
+		}
+	}
+	return nearest
+}
@@ -0,0 +1,99 @@
+package sim
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+// newCombatWorld は弾の命中を調べるための、敵もプレイヤーユニットもいないワールドを作る
+// 敵がいなくてもクリアにならないよう、ウェーブは終わらないようにしておく
+func newCombatWorld() *World {
+	w := NewWorld(Stage{Base: BaseConfig{X: 600, Y: 440, HP: 20}, Waves: []Wave{{TotalFrames: testMaxTicks}}}, 1)
+	w.players = nil
+	return w
+}
+
+// placeTargets は左上の座標が positions になるように弾の的を置き、その ID を返す
+// hostile が true の場合は敵の弾の的としてプレイヤーユニットを、そうでなければ動かない敵を置く
+func placeTargets(w *World, hostile bool, positions []Point) []EntityID {
+	var ids []EntityID
+	for _, p := range positions {
+		if hostile {
+			player := NewPlayer()
+			player.x, player.y, player.postX, player.postY = p.X, p.Y, p.X, p.Y
+			player.holding = true
+			w.addPlayer(player)
+		} else {
+			archetype, _ := archetypes.Lookup("debug")
+			w.addEnemy(NewEnemy(archetype, p.X, p.Y))
+		}
+		ids = append(ids, w.lastID)
+	}
+	return ids
+}
+
+// shoot は (x, y) から最初の的に向けて weapon の弾を撃ち、弾が消えるまでワールドを進めて、それぞれの的が受けたダメージを返す
+func shoot(t *testing.T, w *World, weapon Weapon, x, y float64, targets []EntityID, hostile bool) []int {
+	t.Helper()
+	bullet := weapon.fire(x, y, w.Entity(targets[0]), hostile)
+	if hostile {
+		w.enemyBullets = append(w.enemyBullets, bullet)
+	} else {
+		w.playerBullets = append(w.playerBullets, bullet)
+	}
+	for len(w.playerBullets)+len(w.enemyBullets) > 0 {
+		if w.Status() != Running || w.Tick() > 1000 {
+			t.Fatalf("tick %d: bullet did not vanish (status %s)", w.Tick(), w.Status())
+		}
+		w.Step(Input{})
+	}
+	damage := make([]int, len(targets))
+	for i, id := range targets {
+		switch target := w.Entity(id).(type) {
+		case *Enemy:
+			damage[i] = target.archetype.HP - target.HP
+		case *Player:
+			damage[i] = playerMaxHP - target.HP
+		}
+	}
+	return damage
+}
+
+func TestProjectileKinds(t *testing.T) {
+	weapon := func(kind ProjectileKind) Weapon {
+		return Weapon{Damage: 1, Range: 200, Cooldown: 1, ProjectileSpeed: 8, Projectile: kind}
+	}
+	piercing := weapon(ProjectilePiercing)
+	piercing.Pierce = 2
+	splash := weapon(ProjectileSplash)
+	splash.SplashRadius = 25
+	chain := weapon(ProjectileChain)
+	chain.ChainCount, chain.ChainRange = 2, 50
+
+	for _, tt := range []struct {
+		name      string
+		weapon    Weapon
+		from      Point   // 弾を撃つ位置
+		positions []Point // 的の左上の座標。弾は最初の的を狙う
+		want      []int   // それぞれの的が受けるダメージ
+	}{
+		// 一列に並んだ的を、pierce 体まで貫通する
+		{"pierce", piercing, Point{20, 108}, []Point{{100, 100}, {140, 100}, {180, 100}, {220, 100}}, []int{1, 1, 0, 0}},
+		// 真上から (308, 304) で命中し、中心までの距離が splash_radius と半径の和 (33) 以内の的に当たる
+		{"splash radius", splash, Point{308, 200}, []Point{{300, 300}, {330, 300}, {340, 300}}, []int{1, 1, 0}},
+		// chain_range 以内の的へ、chain_count 回まで連鎖する
+		{"chain count", chain, Point{308, 200}, []Point{{300, 300}, {340, 300}, {380, 300}, {420, 300}}, []int{1, 1, 1, 0}},
+		{"chain range", chain, Point{308, 200}, []Point{{300, 300}, {340, 300}, {400, 300}}, []int{1, 1, 0}},
+	} {
+		for _, hostile := range []bool{false, true} {
+			t.Run(fmt.Sprintf("%s/hostile=%t", tt.name, hostile), func(t *testing.T) {
+				w := newCombatWorld()
+				targets := placeTargets(w, hostile, tt.positions)
+				if got := shoot(t, w, tt.weapon, tt.from.X, tt.from.Y, targets, hostile); !reflect.DeepEqual(got, tt.want) {
+					t.Errorf("damage = %v, want %v", got, tt.want)
+				}
+			})
+		}
+	}
+}
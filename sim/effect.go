@@ -0,0 +1,201 @@
+package sim
+
+import (
+	"fmt"
+	"math"
+)
+
+// EffectID は状態効果の名前。武器や壁はこの名前で与える状態効果を指定する
+type EffectID string
+
+// 組み込みの状態効果
+const (
+	EffectSlow       EffectID = "slow"        // 移動速度が半分になる
+	EffectBurn       EffectID = "burn"        // 0.5 秒ごとに 1 ダメージを受ける
+	EffectStun       EffectID = "stun"        // 移動も攻撃もできなくなる
+	EffectArmorBreak EffectID = "armor_break" // 防御力が 1 下がる。3 つまで重ねがけできる
+	EffectVulnerable EffectID = "vulnerable"  // 受けるダメージが 1.5 倍になる
+)
+
+// StackRule は効果中に同じ状態効果を受けたときの扱い
+type StackRule int
+
+const (
+	StackRefresh   StackRule = iota // 持続時間を最初からやり直す（既定）
+	StackExtend                     // 残りの持続時間に加える
+	StackIntensity                  // MaxStacks まで重ねがけした数だけ効果が強くなり、持続時間を最初からやり直す
+	StackIgnore                     // 効果が切れるまでは新たに受けない
+)
+
+// EffectSpec は状態効果の性能を表す
+// 修正値は重ねがけ 1 つあたりの値で、重ねがけしている数だけ効果が強くなる
+type EffectSpec struct {
+	Duration  int       // 持続時間（フレーム数）
+	Stacking  StackRule // 効果中に同じ状態効果を受けたときの扱い
+	MaxStacks int       // StackIntensity で重ねがけできる数
+
+	SpeedMultiplier  float64 // 移動速度に掛ける値。0 の場合は変えない
+	Armor            int     // 防御力に加える値。受けるダメージは防御力の分だけ減る
+	DamageMultiplier float64 // 受けるダメージに掛ける値。0 の場合は変えない
+	Stun             bool    // true の場合、効果中は移動も攻撃もできない
+
+	// Interval フレームごとに呼ばれる。stacks は重ねがけしている数
+	Interval int
+	OnTick   func(w *World, target Entity, stacks int)
+}
+
+// effectSpecs は登録されている状態効果の一覧
+// 敵の種類のファイルはパッケージの変数の初期化時（init より前）に検証されるので、組み込みの状態効果は init ではなく変数の初期化で登録する
+var effectSpecs = map[EffectID]*EffectSpec{
+	EffectSlow: {Duration: 60, MaxStacks: 1, SpeedMultiplier: 0.5},
+	EffectBurn: {
+		Duration:  120,
+		MaxStacks: 1,
+		Interval:  30,
+		OnTick: func(w *World, target Entity, stacks int) {
+			w.Damage(target, stacks)
+		},
+	},
+	EffectStun:       {Duration: 30, MaxStacks: 1, Stacking: StackIgnore, Stun: true},
+	EffectArmorBreak: {Duration: 300, Stacking: StackIntensity, MaxStacks: 3, Armor: -1},
+	EffectVulnerable: {Duration: 180, MaxStacks: 1, DamageMultiplier: 1.5},
+}
+
+// RegisterEffect は状態効果を id という名前で登録する
+// リプレイや敵の種類のファイルには名前だけが記録されるので、読み込む側でも同じ名前で同じものを登録しておく必要がある
+// 同じ名前を二度登録したり、持続時間が正でなかったりすると panic する
+func RegisterEffect(id EffectID, spec EffectSpec) {
+	if _, ok := effectSpecs[id]; ok {
+		panic(fmt.Sprintf("effect %q is already registered", id))
+	}
+	if spec.Duration <= 0 {
+		panic(fmt.Sprintf("effect %q: duration must be positive: %d", id, spec.Duration))
+	}
+	if spec.MaxStacks < 1 {
+		spec.MaxStacks = 1
+	}
+	effectSpecs[id] = &spec
+}
+
+// Entity の種類ごとに状態効果の一覧を返すためのインターフェース
+type affectable interface {
+	statusEffects() *statusEffects
+}
+
+// activeEffect は効果中の状態効果
+type activeEffect struct {
+	id        EffectID
+	spec      *EffectSpec
+	remaining int // 残りのフレーム数
+	elapsed   int // 受けてから経過したフレーム数
+	stacks    int // 重ねがけしている数
+}
+
+// statusEffects はユニットが受けている状態効果の一覧。受けた順に並ぶ
+type statusEffects []activeEffect
+
+// apply は状態効果 id を受ける。登録されていない状態効果は無視する
+func (s *statusEffects) apply(id EffectID) {
+	spec, ok := effectSpecs[id]
+	if !ok {
+		return
+	}
+	for i := range *s {
+		e := &(*s)[i]
+		if e.id != id {
+			continue
+		}
+		switch spec.Stacking {
+		case StackRefresh:
+			e.remaining = spec.Duration
+		case StackExtend:
+			e.remaining += spec.Duration
+		case StackIntensity:
+			e.stacks = min(e.stacks+1, spec.MaxStacks)
+			e.remaining = spec.Duration
+		}
+		return
+	}
+	*s = append(*s, activeEffect{id: id, spec: spec, remaining: spec.Duration, stacks: 1})
+}
+
+// update は状態効果の時間を 1 フレーム進めて OnTick を呼び、切れた状態効果を取り除く
+func (s *statusEffects) update(w *World, target Entity) {
+	// OnTick の中で状態効果が追加されることがあるので、毎回 *s を参照する
+	for i := 0; i < len(*s); i++ {
+		e := &(*s)[i]
+		e.elapsed++
+		e.remaining--
+		if e.spec.OnTick != nil && e.spec.Interval > 0 && e.elapsed%e.spec.Interval == 0 {
+			e.spec.OnTick(w, target, e.stacks)
+		}
+	}
+	effects := (*s)[:0]
+	for _, e := range *s {
+		if e.remaining > 0 {
+			effects = append(effects, e)
+		}
+	}
+	*s = effects
+}
+
+// speedMultiplier は移動速度に掛ける値を返す
+func (s statusEffects) speedMultiplier() float64 {
+	m := 1.0
+	for _, e := range s {
+		if e.spec.SpeedMultiplier != 0 {
+			m *= math.Pow(e.spec.SpeedMultiplier, float64(e.stacks))
+		}
+	}
+	return m
+}
+
+// stunned は移動も攻撃もできない状態かを返す
+func (s statusEffects) stunned() bool {
+	for _, e := range s {
+		if e.spec.Stun {
+			return true
+		}
+	}
+	return false
+}
+
+// modifyDamage は防御力 armor のユニットが受けるダメージに状態効果の修正値を反映する
+// 防御力が負の場合は、その分だけダメージが増える
+func (s statusEffects) modifyDamage(damage, armor int) int {
+	if damage <= 0 {
+		return 0
+	}
+	m := 1.0
+	for _, e := range s {
+		armor += e.spec.Armor * e.stacks
+		if e.spec.DamageMultiplier != 0 {
+			m *= math.Pow(e.spec.DamageMultiplier, float64(e.stacks))
+		}
+	}
+	return int(math.Round(float64(max(damage-armor, 0)) * m))
+}
+
+// ids は効果中の状態効果の名前を受けた順に返す
+func (s statusEffects) ids() []EffectID {
+	ids := make([]EffectID, len(s))
+	for i, e := range s {
+		ids[i] = e.id
+	}
+	return ids
+}
+
+func (s statusEffects) clone() statusEffects {
+	return append(statusEffects(nil), s...)
+}
+
+// applyEffects は target に状態効果を与える。状態効果を受けない Entity には何もしない
+func applyEffects(target Entity, ids []EffectID) {
+	t, ok := target.(affectable)
+	if !ok {
+		return
+	}
+	for _, id := range ids {
+		t.statusEffects().apply(id)
+	}
+}
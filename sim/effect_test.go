@@ -0,0 +1,133 @@
+package sim
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestArchetypeWeaponEffects(t *testing.T) {
+	registry, err := loadArchetypes(strings.Replace(validArchetype, `"homing"`, `"homing", "effects": ["slow", "armor_break"]`, 1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	archetype, _ := registry.Lookup("test")
+	if got := archetype.Weapon.Effects; len(got) != 2 || got[0] != EffectSlow || got[1] != EffectArmorBreak {
+		t.Errorf("effects = %v", got)
+	}
+
+	// 同梱のボスの弾は命中した自機を鈍足にする
+	boss, ok := archetypes.Lookup("boss")
+	if !ok {
+		t.Fatal("boss archetype not found")
+	}
+	w := NewWorld(Stage{Base: BaseConfig{X: 600, Y: 440, HP: 20}, Waves: []Wave{{TotalFrames: 1}}}, 1)
+	player := &w.players[0]
+	w.hit(player, &boss.Weapon)
+	if got := player.Effects(); len(got) != 1 || got[0] != EffectSlow {
+		t.Errorf("player effects after a boss hit = %v, want [slow]", got)
+	}
+	if want := player.MaxHP() - boss.Weapon.Damage; player.HP != want {
+		t.Errorf("player hp after a boss hit = %d, want %d", player.HP, want)
+	}
+}
+
+// testEffect はテストの間だけ状態効果を登録する
+func testEffect(t *testing.T, id EffectID, spec EffectSpec) {
+	t.Helper()
+	RegisterEffect(id, spec)
+	t.Cleanup(func() { delete(effectSpecs, id) })
+}
+
+func TestStackRules(t *testing.T) {
+	for _, tt := range []struct {
+		name          string
+		rule          StackRule
+		wantRemaining int
+		wantStacks    int
+	}{
+		{"refresh", StackRefresh, 10, 1},
+		{"extend", StackExtend, 16, 1},
+		{"intensity", StackIntensity, 10, 2},
+		{"ignore", StackIgnore, 6, 1},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			id := EffectID("test_" + tt.name)
+			testEffect(t, id, EffectSpec{Duration: 10, Stacking: tt.rule, MaxStacks: 3})
+			var effects statusEffects
+			effects.apply(id)
+			for i := 0; i < 4; i++ {
+				effects.update(nil, nil)
+			}
+			effects.apply(id)
+			if len(effects) != 1 {
+				t.Fatalf("effects = %v, want one effect", effects.ids())
+			}
+			if e := effects[0]; e.remaining != tt.wantRemaining || e.stacks != tt.wantStacks {
+				t.Errorf("remaining = %d, stacks = %d, want %d, %d", e.remaining, e.stacks, tt.wantRemaining, tt.wantStacks)
+			}
+		})
+	}
+
+	// 重ねがけは MaxStacks で止まり、持続時間が過ぎると取り除かれる
+	var effects statusEffects
+	for i := 0; i < 5; i++ {
+		effects.apply(EffectArmorBreak)
+	}
+	if got, want := effects[0].stacks, effectSpecs[EffectArmorBreak].MaxStacks; got != want {
+		t.Errorf("armor break stacks = %d, want %d", got, want)
+	}
+	for i := 0; i < effectSpecs[EffectArmorBreak].Duration; i++ {
+		effects.update(nil, nil)
+	}
+	if len(effects) != 0 {
+		t.Errorf("effects after the duration = %v, want none", effects.ids())
+	}
+}
+
+func TestModifyDamage(t *testing.T) {
+	for _, tt := range []struct {
+		name    string
+		effects []EffectID
+		damage  int
+		armor   int
+		want    int
+	}{
+		{"armor", nil, 3, 1, 2},
+		{"armor above damage", nil, 1, 2, 0},
+		{"no damage", []EffectID{EffectVulnerable}, 0, 0, 0},
+		{"armor break", []EffectID{EffectArmorBreak}, 3, 1, 3},
+		// 防御力が負になった分だけダメージが増える
+		{"stacked armor break", []EffectID{EffectArmorBreak, EffectArmorBreak}, 3, 1, 4},
+		// (3 - 1) * 1.5
+		{"vulnerable", []EffectID{EffectVulnerable}, 3, 1, 3},
+		// (2 - 0) * 1.5
+		{"vulnerable and armor break", []EffectID{EffectVulnerable, EffectArmorBreak}, 2, 1, 3},
+		{"slow", []EffectID{EffectSlow}, 3, 0, 3},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			var effects statusEffects
+			for _, id := range tt.effects {
+				effects.apply(id)
+			}
+			if got := effects.modifyDamage(tt.damage, tt.armor); got != tt.want {
+				t.Errorf("modifyDamage(%d, %d) = %d, want %d", tt.damage, tt.armor, got, tt.want)
+			}
+		})
+	}
+}
+
+// burn は Interval ごとに OnTick でダメージを与える
+func TestBurnDamagesOverTime(t *testing.T) {
+	w := newCombatWorld()
+	archetype, _ := archetypes.Lookup("debug")
+	w.addEnemy(NewEnemy(archetype, 100, 100))
+	enemy := &w.enemies[0]
+	enemy.effects.apply(EffectBurn)
+	spec := effectSpecs[EffectBurn]
+	for i := 0; i < spec.Duration; i++ {
+		enemy.effects.update(w, enemy)
+	}
+	if got, want := archetype.HP-enemy.HP, spec.Duration/spec.Interval; got != want {
+		t.Errorf("burn damage = %d, want %d", got, want)
+	}
+}
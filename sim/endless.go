@@ -0,0 +1,182 @@
+package sim
+
+import "fmt"
+
+// EndlessConfig はエンドレスモードでウェーブを生成するための設定
+// ステージの waves を終えた後は、予算に応じて敵を選んだウェーブを終わりなく生成する
+type EndlessConfig struct {
+	Budget        int            `json:"budget"`         // 最初に生成するウェーブの予算
+	BudgetGrowth  int            `json:"budget_growth"`  // ウェーブごとに増える予算
+	WaveFrames    int            `json:"wave_frames"`    // 生成するウェーブの長さ（フレーム数）
+	SpawnInterval int            `json:"spawn_interval"` // 同じ種類の敵を続けて出現させる間隔（フレーム数）
+	Edges         []string       `json:"edges"`          // 敵を出現させる画面端。ウェーブごとにランダムに選ぶ
+	Enemies       []EndlessEnemy `json:"enemies"`        // 予算で出現させられる敵の種類
+	BossEvery     int            `json:"boss_every"`     // このウェーブ数ごとにボスを出現させる。0 の場合は出現させない
+	Boss          string         `json:"boss"`           // ボスの敵の種類
+}
+
+// EndlessEnemy は予算で出現させられる敵の種類と、1 体あたりのコスト
+type EndlessEnemy struct {
+	Enemy string `json:"enemy"`
+	Cost  int    `json:"cost"`
+}
+
+// 生成するウェーブで最初の敵を出現させるフレーム
+const endlessFirstSpawnFrame = 60
+
+func (c *EndlessConfig) validate(nav *flowField) error {
+	if c.Budget <= 0 {
+		return fmt.Errorf("budget must be positive: %d", c.Budget)
+	}
+	if c.BudgetGrowth < 0 {
+		return fmt.Errorf("budget growth must not be negative: %d", c.BudgetGrowth)
+	}
+	if c.WaveFrames <= endlessFirstSpawnFrame {
+		return fmt.Errorf("wave frames must be greater than %d: %d", endlessFirstSpawnFrame, c.WaveFrames)
+	}
+	if c.SpawnInterval <= 0 {
+		return fmt.Errorf("spawn interval must be positive: %d", c.SpawnInterval)
+	}
+	if len(c.Edges) == 0 {
+		return fmt.Errorf("no edges")
+	}
+	for _, edge := range c.Edges {
+		points, err := edgePoints(edge)
+		if err != nil {
+			return err
+		}
+		for _, p := range points {
+			if !nav.reachable(p.X, p.Y) {
+				return fmt.Errorf("edge %s: position (%g, %g) cannot reach the base", edge, p.X, p.Y)
+			}
+		}
+	}
+	if len(c.Enemies) == 0 {
+		return fmt.Errorf("no enemies")
+	}
+	cheapest := 0
+	for i, enemy := range c.Enemies {
+		if _, ok := archetypes.Lookup(enemy.Enemy); !ok {
+			return fmt.Errorf("enemy %d: unknown enemy %q", i, enemy.Enemy)
+		}
+		if enemy.Cost <= 0 {
+			return fmt.Errorf("enemy %d: cost must be positive: %d", i, enemy.Cost)
+		}
+		if i == 0 || enemy.Cost < cheapest {
+			cheapest = enemy.Cost
+		}
+	}
+	if c.Budget < cheapest {
+		return fmt.Errorf("budget %d cannot buy any enemy (cheapest costs %d)", c.Budget, cheapest)
+	}
+	if c.BossEvery < 0 {
+		return fmt.Errorf("boss every must not be negative: %d", c.BossEvery)
+	}
+	if c.BossEvery > 0 {
+		if _, ok := archetypes.Lookup(c.Boss); !ok {
+			return fmt.Errorf("unknown boss %q", c.Boss)
+		}
+	}
+	return nil
+}
+
+// edgePoints は画面端に沿って、敵が出現しうる位置を BuildCellSize ごとに返す
+func edgePoints(edge string) ([]Point, error) {
+	var points []Point
+	switch edge {
+	case EdgeTop, EdgeBottom:
+		y := 0.0
+		if edge == EdgeBottom {
+			y = FieldHeight
+		}
+		for x := 0.0; x <= FieldWidth; x += BuildCellSize {
+			points = append(points, Point{X: x, Y: y})
+		}
+	case EdgeLeft, EdgeRight:
+		x := 0.0
+		if edge == EdgeRight {
+			x = FieldWidth
+		}
+		for y := 0.0; y <= FieldHeight; y += BuildCellSize {
+			points = append(points, Point{X: x, Y: y})
+		}
+	default:
+		return nil, fmt.Errorf("unknown edge %q", edge)
+	}
+	return points, nil
+}
+
+// generateWave はエンドレスモードの n 番目（0 始まり）のウェーブを生成する
+// 予算の範囲でランダムに敵を選び、種類ごとに画面端から続けて出現させる
+// BossEvery ウェーブごとに、何回目のボスウェーブかと同じ数のボスを加える
+func (c *EndlessConfig) generateWave(n int, r *rng) Wave {
+	counts := make([]int, len(c.Enemies))
+	budget := c.Budget + c.BudgetGrowth*n
+	for {
+		var affordable []int
+		for i, enemy := range c.Enemies {
+			if enemy.Cost <= budget {
+				affordable = append(affordable, i)
+			}
+		}
+		if len(affordable) == 0 {
+			break
+		}
+		i := affordable[r.Intn(len(affordable))]
+		counts[i]++
+		budget -= c.Enemies[i].Cost
+	}
+
+	wave := Wave{TotalFrames: c.WaveFrames}
+	if c.BossEvery > 0 && (n+1)%c.BossEvery == 0 {
+		wave.EnemySpawns = append(wave.EnemySpawns, c.spawn(c.Boss, (n+1)/c.BossEvery, r))
+	}
+	for i, count := range counts {
+		if count > 0 {
+			wave.EnemySpawns = append(wave.EnemySpawns, c.spawn(c.Enemies[i].Enemy, count, r))
+		}
+	}
+	return wave
+}
+
+// spawn は enemy を count 体、ランダムに選んだ画面端から出現させる設定を返す
+// ウェーブの時間内に出現し終えるよう、必要なら出現間隔を詰める
+func (c *EndlessConfig) spawn(enemy string, count int, r *rng) EnemySpawnInfo {
+	interval := c.SpawnInterval
+	if count > 1 {
+		interval = min(interval, (c.WaveFrames-endlessFirstSpawnFrame-1)/(count-1))
+	}
+	return EnemySpawnInfo{
+		SpawnFrame: endlessFirstSpawnFrame,
+		Enemy:      enemy,
+		Edge:       c.Edges[r.Intn(len(c.Edges))],
+		Count:      count,
+		Interval:   interval,
+	}
+}
+
+// Endless はエンドレスモードのステージかを返す
+func (w *World) Endless() bool {
+	return w.currentStage.Endless != nil
+}
+
+// wave は i 番目（0 始まり）のウェーブを返す。ステージの waves の後は生成したウェーブを返す
+func (w *World) wave(i int) *Wave {
+	if i < len(w.currentStage.Waves) {
+		return &w.currentStage.Waves[i]
+	}
+	return &w.generatedWaves[i-len(w.currentStage.Waves)]
+}
+
+// hasWave は i 番目（0 始まり）のウェーブがあるかを返す。エンドレスモードでは常に true を返す
+func (w *World) hasWave(i int) bool {
+	return i < len(w.currentStage.Waves) || w.currentStage.Endless != nil
+}
+
+// prepareWave は i 番目（0 始まり）のウェーブを始められるように、まだ生成していないウェーブを生成する
+// 乱数を使うので、ウェーブを始めるときにだけ呼ぶ
+func (w *World) prepareWave(i int) {
+	for n := len(w.generatedWaves); len(w.currentStage.Waves)+n <= i; n++ {
+		w.generatedWaves = append(w.generatedWaves, w.currentStage.Endless.generateWave(n, &w.rng))
+	}
+}
@@ -0,0 +1,104 @@
+package sim
+
+import "testing"
+
+// loadEndless はテスト用に、本拠地が壊れずプレイヤーユニットもいないエンドレスのステージのワールドを作る
+func loadEndless(t *testing.T) *World {
+	t.Helper()
+	stage, err := LoadDefaultStage("endless")
+	if err != nil {
+		t.Fatal(err)
+	}
+	stage.Base.HP = 1 << 30
+	w := NewWorld(stage, 1)
+	w.players = nil
+	return w
+}
+
+func TestEndlessGeneratesEscalatingWaves(t *testing.T) {
+	w := loadEndless(t)
+	for w.Wave() < 10 {
+		w.Step(Input{})
+	}
+	if w.Status() != Running {
+		t.Fatalf("status = %s, want running", w.Status())
+	}
+	config := w.Stage().Endless
+	for i, wave := range w.generatedWaves {
+		cost, bosses := 0, 0
+		for _, spawn := range wave.EnemySpawns {
+			if spawn.Enemy == config.Boss {
+				bosses += spawn.count()
+				continue
+			}
+			for _, enemy := range config.Enemies {
+				if enemy.Enemy == spawn.Enemy {
+					cost += enemy.Cost * spawn.count()
+				}
+			}
+		}
+		// 最も安い敵のコストは 1 なので、予算はちょうど使い切る
+		if budget := config.Budget + config.BudgetGrowth*i; cost != budget {
+			t.Errorf("wave %d: spent %d of budget %d", i, cost, budget)
+		}
+		wantBosses := 0
+		if (i+1)%config.BossEvery == 0 {
+			wantBosses = (i + 1) / config.BossEvery
+		}
+		if bosses != wantBosses {
+			t.Errorf("wave %d: %d bosses, want %d", i, bosses, wantBosses)
+		}
+	}
+}
+
+// エンドレスモードでは、始まっているウェーブの敵が出現し終えるまで次のウェーブを呼べない
+func TestEndlessNextWaveWaitsForSpawns(t *testing.T) {
+	w := loadEndless(t)
+	next := Input{Commands: []Command{CommandNextWave}}
+	money := w.Money()
+	for {
+		if w.CanCallNextWave() || w.NextWaveBonus() != 0 {
+			t.Fatalf("tick %d: next wave can be called while wave 1 is spawning", w.Tick())
+		}
+		bonus := w.wave(0).TotalFrames - (w.activeWaves[0].frame + 1)
+		w.Step(next)
+		if w.pendingSpawns(w.activeWaves[0]) == 0 {
+			// 最後の敵が出現したティックから呼べる
+			if want := money + bonus*nextWaveBonusPerSecond/60; w.Wave() != 2 || w.Money() != want {
+				t.Errorf("wave = %d, money = %d after wave 1 finished spawning, want 2, %d", w.Wave(), w.Money(), want)
+			}
+			break
+		}
+		if w.Wave() != 1 || w.Money() != money {
+			t.Fatalf("tick %d: wave = %d, money = %d while wave 1 is spawning, want 1, %d", w.Tick(), w.Wave(), w.Money(), money)
+		}
+	}
+	if w.CanCallNextWave() {
+		t.Errorf("next wave can be called again right after calling wave 2")
+	}
+}
+
+// 早めに呼んだだけのウェーブは片付けたウェーブに数えない
+func TestCallingWavesEarlyDoesNotClearThem(t *testing.T) {
+	w := loadEndless(t)
+	for w.Tick() < 3000 {
+		w.Step(Input{Commands: []Command{CommandNextWave}})
+	}
+	if w.Wave() <= 1 {
+		t.Fatalf("wave = %d, want waves called early", w.Wave())
+	}
+	// プレイヤーユニットがいないので、最初のウェーブの敵も残っている
+	if got := w.ClearedWaves(); got != 0 {
+		t.Errorf("cleared waves = %d with every enemy alive, want 0", got)
+	}
+
+	// 敵がいなくなると、出現し終えたウェーブまでが片付いたことになる
+	w.enemies = nil
+	want := w.Wave()
+	if a := w.latestWave(); w.pendingSpawns(*a) > 0 {
+		want = a.index
+	}
+	if got := w.ClearedWaves(); got != want {
+		t.Errorf("cleared waves = %d after removing the enemies, want %d", got, want)
+	}
+}
@@ -0,0 +1,182 @@
+package sim
+
+import (
+	"math"
+)
+
+type Enemy struct {
+	id        EntityID
+	archetype *EnemyArchetype
+
+	x, y    float64
+	speed   float64
+	HP      int
+	active  bool
+	reached bool // leak_zone に到達したかどうか
+	wave    int  // 出現したウェーブ（0 始まり）
+
+	effects statusEffects
+
+	collidedWalls []string
+
+	framesSinceLastBullet int
+}
+
+// NewEnemy は指定した種類の敵を (x, y) に生成する
+func NewEnemy(archetype *EnemyArchetype, x, y float64) Enemy {
+	return Enemy{
+		archetype:     archetype,
+		x:             x,
+		y:             y,
+		speed:         archetype.Speed,
+		HP:            archetype.HP,
+		active:        true,
+		collidedWalls: []string{},
+	}
+}
+
+func (e *Enemy) ID() EntityID {
+	return e.id
+}
+
+func (e *Enemy) GetX() float64 {
+	return e.x
+}
+
+func (e *Enemy) GetY() float64 {
+	return e.y
+}
+
+// currentSpeed は状態効果を反映した移動速度を返す
+func (e *Enemy) currentSpeed() float64 {
+	return e.speed * e.effects.speedMultiplier()
+}
+
+// Effects は敵が受けている状態効果の名前を受けた順に返す
+func (e *Enemy) Effects() []EffectID {
+	return e.effects.ids()
+}
+
+func (e *Enemy) statusEffects() *statusEffects {
+	return &e.effects
+}
+
+// Archetype は敵の種類を返す
+func (e *Enemy) Archetype() *EnemyArchetype {
+	return e.archetype
+}
+
+func (e *Enemy) Update(w *World) {
+	// 壁との当たり判定。触れている壁は少しずつすり減る。block の壁は敵の攻撃でだけ壊れる
+	for i := range w.walls {
+		wall := &w.walls[i]
+		if !e.touchesWall(wall) {
+			continue
+		}
+		if wall.kind != WallBlock {
+			wall.hp -= wallContactWear
+		}
+		if effect := wallSpecs[wall.kind].effect; effect != "" && e.firstContact(wall) {
+			e.effects.apply(effect)
+		}
+		// 壁を引いた向きに押し流す。障害物やタワーのマス、フィールドの外には押し出さない
+		if r := e.GetRadius(); wall.kind == WallRedirect && w.nav.open(e.x+r, e.y+r) {
+			dx, dy := wall.direction()
+			e.x, e.y = w.stepInField(e, dx*wallRedirectSpeed, dy*wallRedirectSpeed)
+		}
+	}
+
+	e.effects.update(w, e)
+
+	// leak_zone に到達したかどうかを判定
+	if zone := w.currentStage.LoseConditions.LeakZone; zone != nil && !e.reached {
+		r := e.GetRadius()
+		e.reached = zone.contains(e.x+r, e.y+r)
+	}
+
+	e.framesSinceLastBullet++
+}
+
+type Point struct {
+	X float64 `json:"x"`
+	Y float64 `json:"y"`
+}
+
+// touchesWall は敵が壁に触れているかを返す
+func (e *Enemy) touchesWall(wall *Wall) bool {
+	// 壁を無視する種類の敵は衝突しない
+	if e.archetype.HasFlag(FlagIgnoreWalls) {
+		return false
+	}
+
+	// 線分（壁）と矩形（敵）の当たり判定
+	rectTopLeft := Point{e.x, e.y}
+	rectBottomRight := Point{e.x + e.archetype.Size, e.y + e.archetype.Size}
+	return lineIntersectsRect(wall.x1, wall.y1, wall.x2, wall.y2, rectTopLeft, rectBottomRight)
+}
+
+// firstContact は触れている壁に初めて触れたかを返す。同じ壁の効果を何度も受けないようにする
+func (e *Enemy) firstContact(wall *Wall) bool {
+	// すでに衝突している壁に再衝突しているかのチェック
+	for _, id := range e.collidedWalls {
+		if id == wall.id {
+			return false
+		}
+	}
+	// 衝突した壁のIDを保存
+	e.collidedWalls = append(e.collidedWalls, wall.id)
+	return true
+}
+
+// 線分（x1, y1, x2, y2）と矩形（rectTopLeft, rectBottomRight）の当たり判定
+func lineIntersectsRect(x1, y1, x2, y2 float64, rectTopLeft, rectBottomRight Point) bool {
+	// 線分と矩形の4つの辺との当たり判定を行う
+	// この実装は簡易的なもので、より高精度な判定が必要な場合は別の方法を検討してください。
+	return lineIntersectsLine(x1, y1, x2, y2, rectTopLeft.X, rectTopLeft.Y, rectBottomRight.X, rectTopLeft.Y) || // 上辺
+		lineIntersectsLine(x1, y1, x2, y2, rectTopLeft.X, rectBottomRight.Y, rectBottomRight.X, rectBottomRight.Y) || // 下辺
+		lineIntersectsLine(x1, y1, x2, y2, rectTopLeft.X, rectTopLeft.Y, rectTopLeft.X, rectBottomRight.Y) || // 左辺
+		lineIntersectsLine(x1, y1, x2, y2, rectBottomRight.X, rectTopLeft.Y, rectBottomRight.X, rectBottomRight.Y) // 右辺
+}
+
+// 線分（x1, y1, x2, y2）と線分（x3, y3, x4, y4）の当たり判定
+func lineIntersectsLine(x1, y1, x2, y2, x3, y3, x4, y4 float64) bool {
+	// 以下の関数は、2つの数の乗算の結果が正か負かを計算します。
+	crossProductSign := func(x1, y1, x2, y2, x3, y3 float64) float64 {
+		return (x2-x1)*(y3-y1) - (x3-x1)*(y2-y1)
+	}
+
+	d1 := crossProductSign(x1, y1, x2, y2, x3, y3)
+	d2 := crossProductSign(x1, y1, x2, y2, x4, y4)
+	d3 := crossProductSign(x3, y3, x4, y4, x1, y1)
+	d4 := crossProductSign(x3, y3, x4, y4, x2, y2)
+
+	// 2つの線分が交差する条件は、各線分の両端が他の線分と異なる側に位置することです。
+	// これは、crossProductSignの結果の符号がd1とd2、またはd3とd4で異なる場合に真となります。
+	return ((d1 > 0 && d2 < 0) || (d1 < 0 && d2 > 0)) && ((d3 > 0 && d4 < 0) || (d3 < 0 && d4 > 0))
+}
+
+// 弾が敵に当たったかどうかを判定するメソッド
+func (e *Enemy) IsHit(bulletX, bulletY float64) bool {
+	enemyRadius := e.GetRadius()
+
+	// 敵と弾の中心間の距離を計算
+	dx := e.x + enemyRadius - bulletX
+	dy := e.y + enemyRadius - bulletY
+	distance := math.Sqrt(dx*dx + dy*dy)
+
+	// 2つの円の半径の合計よりも距離が小さい場合、当たりと判定
+	return distance < (enemyRadius + bulletRadius)
+}
+
+func (e *Enemy) GetRadius() float64 {
+	return e.archetype.Size / 2
+}
+
+func (e *Enemy) GetPosition() (x, y int) {
+	return int(e.x), int(e.y)
+}
+
+func (e *Enemy) GetSize() (width, height int) {
+	radius := e.GetRadius()
+	return int(radius * 2), int(radius * 2)
+}
@@ -0,0 +1,80 @@
+package sim
+
+// EntityID はワールド上のユニットを識別する ID
+// スライスの詰め直しで位置が変わっても同じユニットを指し続ける。0 はどのユニットも指さない
+type EntityID int
+
+// Entity は ID で参照できるワールド上のユニット
+type Entity interface {
+	ID() EntityID
+	GetX() float64
+	GetY() float64
+	GetRadius() float64
+}
+
+// newID は新しいユニットに割り当てる ID を返す
+func (w *World) newID() EntityID {
+	w.lastID++
+	return w.lastID
+}
+
+// addEnemy は ID を割り当てて敵をワールドに追加する
+func (w *World) addEnemy(enemy Enemy) {
+	enemy.id = w.newID()
+	w.enemyIndex[enemy.id] = len(w.enemies)
+	w.enemies = append(w.enemies, enemy)
+}
+
+// addPlayer は ID を割り当ててプレイヤーユニットをワールドに追加する
+func (w *World) addPlayer(player Player) {
+	player.id = w.newID()
+	w.playerIndex[player.id] = len(w.players)
+	w.players = append(w.players, player)
+}
+
+// reindexEnemies は敵のスライスを詰め直した後に ID からの索引を作り直す
+func (w *World) reindexEnemies() {
+	for id := range w.enemyIndex {
+		delete(w.enemyIndex, id)
+	}
+	for i := range w.enemies {
+		w.enemyIndex[w.enemies[i].id] = i
+	}
+}
+
+// reindexPlayers はプレイヤーユニットのスライスを詰め直した後に ID からの索引を作り直す
+func (w *World) reindexPlayers() {
+	for id := range w.playerIndex {
+		delete(w.playerIndex, id)
+	}
+	for i := range w.players {
+		w.playerIndex[w.players[i].id] = i
+	}
+}
+
+// enemyByID は ID に対応する生存中の敵を返す。いなければ nil を返す
+func (w *World) enemyByID(id EntityID) *Enemy {
+	i, ok := w.enemyIndex[id]
+	if !ok || !w.enemies[i].active {
+		return nil
+	}
+	return &w.enemies[i]
+}
+
+// Entity は ID に対応するユニットを返す。倒された敵など、存在しない場合は nil を返す
+// 戻り値は *Enemy, *Player, *Tower, *Base のいずれか
+func (w *World) Entity(id EntityID) Entity {
+	if enemy := w.enemyByID(id); enemy != nil {
+		return enemy
+	}
+	if i, ok := w.playerIndex[id]; ok {
+		return &w.players[i]
+	}
+	if i, ok := w.towerIndex[id]; ok {
+		return &w.towers[i]
+	}
+	if id != 0 && id == w.base.id {
+		return w.base
+	}
+	return nil
+}
@@ -0,0 +1,75 @@
+package sim
+
+import (
+	"math"
+	"sort"
+)
+
+// 空間分割に使うセルの一辺の長さ
+const gridCellSize = 64
+
+// spatialGrid はフィールドを一定サイズのセルに分割し、各セルにいる敵を保持する
+// 当たり判定や攻撃範囲の検索で、近くのセルにいる敵だけを調べられるようにする
+type spatialGrid struct {
+	cols, rows int
+	cells      [][]int // セルごとの enemies 内の位置
+	maxRadius  float64 // 登録されている敵の半径の最大値
+}
+
+func newSpatialGrid() *spatialGrid {
+	cols := int(math.Ceil(FieldWidth/gridCellSize)) + 1
+	rows := int(math.Ceil(FieldHeight/gridCellSize)) + 1
+	return &spatialGrid{
+		cols:  cols,
+		rows:  rows,
+		cells: make([][]int, cols*rows),
+	}
+}
+
+// cell は座標を含むセルの列と行を返す。フィールドの外はいちばん端のセルに含める
+func (g *spatialGrid) cell(x, y float64) (col, row int) {
+	col = int(math.Floor(x / gridCellSize))
+	row = int(math.Floor(y / gridCellSize))
+	return clamp(col, 0, g.cols-1), clamp(row, 0, g.rows-1)
+}
+
+// rebuild は敵の中心座標をもとにセルを作り直す。毎ティック、敵が移動した後に呼ぶ
+func (g *spatialGrid) rebuild(enemies []Enemy) {
+	for i := range g.cells {
+		g.cells[i] = g.cells[i][:0]
+	}
+	g.maxRadius = 0
+	for i := range enemies {
+		e := &enemies[i]
+		radius := e.GetRadius()
+		col, row := g.cell(e.x+radius, e.y+radius)
+		g.cells[row*g.cols+col] = append(g.cells[row*g.cols+col], i)
+		g.maxRadius = math.Max(g.maxRadius, radius)
+	}
+}
+
+// query は中心が (x, y) から radius 以内にいる可能性のある敵の位置を昇順で dst に追加して返す
+func (g *spatialGrid) query(dst []int, x, y, radius float64) []int {
+	minCol, minRow := g.cell(x-radius, y-radius)
+	maxCol, maxRow := g.cell(x+radius, y+radius)
+
+	start := len(dst)
+	for row := minRow; row <= maxRow; row++ {
+		for col := minCol; col <= maxCol; col++ {
+			dst = append(dst, g.cells[row*g.cols+col]...)
+		}
+	}
+	// セルの走査順によらず、enemies の並び順で結果を返す
+	sort.Ints(dst[start:])
+	return dst
+}
+
+func clamp(v, min, max int) int {
+	if v < min {
+		return min
+	}
+	if v > max {
+		return max
+	}
+	return v
+}
@@ -0,0 +1,73 @@
+package sim
+
+import (
+	"math"
+	"sort"
+	"testing"
+)
+
+// 空間分割で検索した結果が、すべての敵を調べた結果を取りこぼしていないか確認する
+func TestGridQueryMatchesBruteForce(t *testing.T) {
+	r := newRNG(1)
+	ids := []string{"a", "runner", "tank", "boss"}
+	var enemies []Enemy
+	for i := 0; i < 200; i++ {
+		archetype, _ := archetypes.Lookup(ids[r.Intn(len(ids))])
+		// フィールドの少し外にいる敵も混ぜる
+		x := r.Float64()*(FieldWidth+80) - 40
+		y := r.Float64()*(FieldHeight+80) - 40
+		enemies = append(enemies, NewEnemy(archetype, x, y))
+	}
+	grid := newSpatialGrid()
+	grid.rebuild(enemies)
+
+	for n := 0; n < 500; n++ {
+		x, y := r.Float64()*FieldWidth, r.Float64()*FieldHeight
+		radius := r.Float64() * 200
+		got := grid.query(nil, x, y, radius)
+		if !sort.IntsAreSorted(got) {
+			t.Fatalf("query(%g, %g, %g) = %v, not sorted", x, y, radius, got)
+		}
+		found := map[int]bool{}
+		for _, i := range got {
+			if found[i] {
+				t.Fatalf("query(%g, %g, %g) returned %d twice", x, y, radius, i)
+			}
+			found[i] = true
+		}
+		for i := range enemies {
+			e := &enemies[i]
+			if math.Hypot(e.x+e.GetRadius()-x, e.y+e.GetRadius()-y) <= radius && !found[i] {
+				t.Fatalf("query(%g, %g, %g) missed enemy %d at (%g, %g)", x, y, radius, i, e.x, e.y)
+			}
+		}
+	}
+}
+
+// nearestEnemyTo は空間分割を使っても、すべての敵から探した場合と同じ敵を返す
+func TestNearestEnemyMatchesBruteForce(t *testing.T) {
+	r := newRNG(2)
+	w := newCombatWorld()
+	archetype, _ := archetypes.Lookup("a")
+	for i := 0; i < 100; i++ {
+		w.addEnemy(NewEnemy(archetype, r.Float64()*FieldWidth, r.Float64()*FieldHeight))
+	}
+	w.grid.rebuild(w.enemies)
+	none := func(Entity) bool { return false }
+
+	for n := 0; n < 500; n++ {
+		x, y := r.Float64()*FieldWidth, r.Float64()*FieldHeight
+		maxDistance := r.Float64() * 150
+		var want *Enemy
+		wantDistance := maxDistance
+		for i := range w.enemies {
+			e := &w.enemies[i]
+			if d := math.Hypot(e.x-x, e.y-y); d <= wantDistance {
+				want, wantDistance = e, d
+			}
+		}
+		if got := w.nearestEnemyTo(x, y, maxDistance, none); got != want {
+			t.Fatalf("nearestEnemyTo(%g, %g, %g) = %v, want %v", x, y, maxDistance, got, want)
+		}
+	}
+}
@@ -0,0 +1,45 @@
+package sim
+
+// Command はプレイヤーがシミュレーションに対して発行する操作
+type Command string
+
+const (
+	CommandRecoverHP Command = "recover_hp" // 本拠地の HP を回復する
+	CommandTrainUnit Command = "train_unit" // ユニットを訓練する
+
+	CommandHastenRespawn Command = "hasten_respawn" // 最も早く復活するユニットをお金を払ってすぐに復活させる
+	CommandNextWave      Command = "next_wave"      // 次のウェーブを早めに呼び、早めた時間に応じたお金をもらう
+)
+
+// Order は指定したプレイヤーユニットへの指示
+type Order struct {
+	// 指示の種類。省略時は move
+	Kind OrderKind `json:"kind,omitempty"`
+	// 指示を受けるユニット。存在しない ID や、指示に従えないユニットの ID は無視する
+	// タワーは targeting にだけ従う
+	Units []EntityID `json:"units"`
+	// 移動先。複数のユニットに指示した場合は、移動先の周りに並ぶように散らばる。hold と targeting では使わない
+	Target Point `json:"target"`
+	// true の場合は今の指示を取り消さず、経由地として後ろに追加する
+	Queue bool `json:"queue,omitempty"`
+	// targeting: 攻撃対象の選び方の名前。登録されていない名前の場合は何もしない
+	Targeting string `json:"targeting,omitempty"`
+}
+
+// Input は 1 ティック分の操作をまとめたもの
+// マウスやタッチなどの入力デバイスの状態は呼び出し側でこの形に変換する
+type Input struct {
+	// このティックで出すユニットへの指示
+	Orders []Order `json:"orders,omitempty"`
+	// このティックでタワーを置くマス
+	Towers []TowerPlacement `json:"towers,omitempty"`
+	// このティックで引く壁
+	Walls []WallPlacement `json:"walls,omitempty"`
+	// このティックで実行するコマンド
+	Commands []Command `json:"commands,omitempty"`
+}
+
+// empty は何も操作がないかどうかを返す
+func (in *Input) empty() bool {
+	return len(in.Orders) == 0 && len(in.Towers) == 0 && len(in.Walls) == 0 && len(in.Commands) == 0
+}
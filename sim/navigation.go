@@ -0,0 +1,374 @@
+package sim
+
+import (
+	"container/heap"
+	"math"
+)
+
+// 経路探索のマスの移動コスト。斜めは縦横の約 √2 倍
+const (
+	navStraightCost = 10
+	navDiagonalCost = 14
+)
+
+// 本拠地にたどり着けないマスの距離
+const navUnreachable = math.MaxInt32
+
+// flowField は各マスから本拠地までの最短距離と、次に進むマスを保持する
+// マスはタワーを置くグリッドと同じで、添字は row*BuildGridCols+col
+type flowField struct {
+	blocked []bool // 通れないマス
+	goal    []bool // 本拠地に重なるマス
+	dist    []int  // 本拠地までの距離
+	next    []int  // 本拠地に向かって次に進むマス。本拠地のマスとたどり着けないマスは -1
+}
+
+// newFlowField はステージの障害物と本拠地から流れ場を作る
+func newFlowField(stage Stage) *flowField {
+	n := BuildGridCols * BuildGridRows
+	f := &flowField{
+		blocked: make([]bool, n),
+		goal:    make([]bool, n),
+		dist:    make([]int, n),
+		next:    make([]int, n),
+	}
+	base := NewBase(stage.Base)
+	size := base.GetRadius() * 2
+	for c := range f.goal {
+		x, y := CellRect(c%BuildGridCols, c/BuildGridCols)
+		f.goal[c] = rectsOverlap(x, y, BuildCellSize, BuildCellSize, base.x, base.y, size, size)
+		for _, obstacle := range stage.Obstacles {
+			if obstacle.overlaps(x, y, BuildCellSize, BuildCellSize) {
+				f.blocked[c] = true
+			}
+		}
+	}
+	f.rebuild()
+	return f
+}
+
+// towards は同じマスを通れないものとして、マス goal に向かう流れ場を作る
+func (f *flowField) towards(goal int) *flowField {
+	n := len(f.blocked)
+	t := &flowField{
+		blocked: f.blocked, // 書き換えないので共有する
+		goal:    make([]bool, n),
+		dist:    make([]int, n),
+		next:    make([]int, n),
+	}
+	t.goal[goal] = true
+	t.rebuild()
+	return t
+}
+
+// rebuild はすべてのマスの距離を計算し直す
+func (f *flowField) rebuild() {
+	q := &navQueue{}
+	for c := range f.dist {
+		f.dist[c] = navUnreachable
+		f.next[c] = -1
+		if f.goal[c] && !f.blocked[c] {
+			f.dist[c] = 0
+			heap.Push(q, navItem{cell: c})
+		}
+	}
+	f.propagate(q)
+}
+
+// block はマスを通れなくし、影響を受けるマスの距離だけを計算し直す
+func (f *flowField) block(c int) {
+	if f.blocked[c] {
+		return
+	}
+	f.blocked[c] = true
+
+	// c を通って本拠地に向かっていたマス（c 自身を含む）と、c の角をかすめて斜めに進んでいたマス、
+	// およびそれらを通って本拠地に向かっていたマスだけが遠くなる
+	roots := []int{c}
+	f.forEachCellAround(c, func(n int) {
+		if next := f.next[n]; next != -1 && !f.adjacent(n, next) {
+			roots = append(roots, n)
+		}
+	})
+	affected := f.upstream(roots)
+	for _, a := range affected {
+		f.dist[a] = navUnreachable
+		f.next[a] = -1
+	}
+	// 影響を受けなかった隣のマスから距離を伝え直す
+	q := &navQueue{}
+	for _, a := range affected {
+		if f.blocked[a] {
+			continue
+		}
+		f.forEachNeighbor(a, func(n, cost int) {
+			if f.dist[n] != navUnreachable && f.dist[n]+cost < f.dist[a] {
+				f.dist[a] = f.dist[n] + cost
+				f.next[a] = n
+			}
+		})
+		if f.dist[a] != navUnreachable {
+			heap.Push(q, navItem{cell: a, dist: f.dist[a]})
+		}
+	}
+	f.propagate(q)
+}
+
+// propagate はキューにあるマスから距離を広げていく（ダイクストラ法）
+func (f *flowField) propagate(q *navQueue) {
+	for q.Len() > 0 {
+		item := heap.Pop(q).(navItem)
+		if item.dist > f.dist[item.cell] {
+			continue
+		}
+		f.forEachNeighbor(item.cell, func(n, cost int) {
+			if d := item.dist + cost; d < f.dist[n] {
+				f.dist[n] = d
+				f.next[n] = item.cell
+				heap.Push(q, navItem{cell: n, dist: d})
+			}
+		})
+	}
+}
+
+// upstream は次に進むマスをたどると roots のいずれかに行き着くマスを、roots 自身を含めて返す
+func (f *flowField) upstream(roots []int) []int {
+	const (
+		unknown = iota
+		through
+		notThrough
+	)
+	state := make([]int, len(f.next))
+	for _, c := range roots {
+		state[c] = through
+	}
+	var cells []int
+	var path []int
+	for start := range f.next {
+		path = path[:0]
+		cur := start
+		for cur != -1 && state[cur] == unknown {
+			path = append(path, cur)
+			cur = f.next[cur]
+		}
+		result := notThrough
+		if cur != -1 && state[cur] == through {
+			result = through
+		}
+		for _, p := range path {
+			state[p] = result
+		}
+	}
+	for i, s := range state {
+		if s == through {
+			cells = append(cells, i)
+		}
+	}
+	return cells
+}
+
+// forEachNeighbor は c から通れる隣のマスと、そこへの移動コストを渡して fn を呼び出す
+// 斜めの移動は、間にある縦横のマスがどちらも通れる場合だけ許す
+func (f *flowField) forEachNeighbor(c int, fn func(n, cost int)) {
+	col, row := c%BuildGridCols, c/BuildGridCols
+	open := func(col, row int) bool {
+		return col >= 0 && col < BuildGridCols && row >= 0 && row < BuildGridRows && !f.blocked[row*BuildGridCols+col]
+	}
+	for dy := -1; dy <= 1; dy++ {
+		for dx := -1; dx <= 1; dx++ {
+			if dx == 0 && dy == 0 || !open(col+dx, row+dy) {
+				continue
+			}
+			cost := navStraightCost
+			if dx != 0 && dy != 0 {
+				if !open(col+dx, row) || !open(col, row+dy) {
+					continue
+				}
+				cost = navDiagonalCost
+			}
+			fn((row+dy)*BuildGridCols+col+dx, cost)
+		}
+	}
+}
+
+// forEachCellAround は c を囲むフィールド内の 8 マスを渡して fn を呼び出す。通れないマスも含む
+func (f *flowField) forEachCellAround(c int, fn func(n int)) {
+	col, row := c%BuildGridCols, c/BuildGridCols
+	for dy := -1; dy <= 1; dy++ {
+		for dx := -1; dx <= 1; dx++ {
+			x, y := col+dx, row+dy
+			if dx == 0 && dy == 0 || x < 0 || x >= BuildGridCols || y < 0 || y >= BuildGridRows {
+				continue
+			}
+			fn(y*BuildGridCols + x)
+		}
+	}
+}
+
+// adjacent は a から n へ直接移動できるかを返す
+func (f *flowField) adjacent(a, n int) bool {
+	found := false
+	f.forEachNeighbor(a, func(m, _ int) {
+		if m == n {
+			found = true
+		}
+	})
+	return found
+}
+
+// cellIndex は座標 (x, y) を含むマスの添字を返す。フィールドの外の座標は一番近いマスに寄せる
+func cellIndex(x, y float64) int {
+	col, row := CellAt(x, y)
+	return clamp(row, 0, BuildGridRows-1)*BuildGridCols + clamp(col, 0, BuildGridCols-1)
+}
+
+// reachable は (x, y) から本拠地にたどり着けるかを返す
+func (f *flowField) reachable(x, y float64) bool {
+	return f.dist[cellIndex(x, y)] != navUnreachable
+}
+
+// direction は中心が (x, y) にある敵が本拠地に向かって進む向きを返す
+// 通れないマスに入り込んでいる場合は、周りの通れるマスのうち本拠地に最も近いマスに向かって抜け出す
+// 本拠地のマスにいる場合とたどり着けない場合は ok が false になる
+func (f *flowField) direction(x, y float64) (dx, dy float64, ok bool) {
+	c := cellIndex(x, y)
+	next := f.next[c]
+	if f.blocked[c] {
+		next = f.exit(c)
+	}
+	if next == -1 {
+		return 0, 0, false
+	}
+	nx, ny := CellRect(next%BuildGridCols, next/BuildGridCols)
+	dx = nx + BuildCellSize/2 - x
+	dy = ny + BuildCellSize/2 - y
+	dist := math.Sqrt(dx*dx + dy*dy)
+	if dist == 0 {
+		return 0, 0, false
+	}
+	return dx / dist, dy / dist, true
+}
+
+// exit は通れないマス c の周りの通れるマスのうち、本拠地に最も近いマスを返す。ない場合は -1 を返す
+func (f *flowField) exit(c int) int {
+	exit := -1
+	f.forEachCellAround(c, func(n int) {
+		if !f.blocked[n] && (exit == -1 || f.dist[n] < f.dist[exit]) {
+			exit = n
+		}
+	})
+	return exit
+}
+
+// open は (x, y) がフィールドの中の通れるマスにあるかを返す
+func (f *flowField) open(x, y float64) bool {
+	return x >= 0 && x < FieldWidth && y >= 0 && y < FieldHeight && !f.blocked[cellIndex(x, y)]
+}
+
+func (f *flowField) clone() *flowField {
+	return &flowField{
+		blocked: append([]bool(nil), f.blocked...),
+		goal:    f.goal, // 書き換えないので共有する
+		dist:    append([]int(nil), f.dist...),
+		next:    append([]int(nil), f.next...),
+	}
+}
+
+// moveEnemy は敵をターゲットに向けて 1 ティック分移動させる
+// 流れ場に沿って障害物やタワーを避け、通れないマスやフィールドの外には入らない
+// block の壁を横切る場合は移動せず、代わりに壁を攻撃する
+func (w *World) moveEnemy(enemy *Enemy, target Entity) {
+	fromX, fromY := enemy.x, enemy.y
+	dx, dy := w.enemyDirection(enemy, target)
+	speed := enemy.currentSpeed()
+	enemy.x, enemy.y = w.stepInField(enemy, dx*speed, dy*speed)
+
+	// 壁への攻撃も他の攻撃と同じく武器の発射間隔ごとに行う
+	if wall := w.blockingWall(enemy, fromX, fromY); wall != nil {
+		enemy.x, enemy.y = fromX, fromY
+		if weapon := &enemy.archetype.Weapon; weapon.ready(enemy.framesSinceLastBullet) {
+			wall.hp -= weapon.Damage
+			enemy.framesSinceLastBullet = 0
+		}
+	}
+}
+
+// stepInField は敵を (dx, dy) だけ動かした位置を返す
+// 動いた先の中心が通れないマスやフィールドの外になる場合は、縦か横の一方だけ動かして壁沿いに滑らせ、
+// それもできなければ動かさない。すでに通れないマスやフィールドの外にいる場合（画面端からの出現直後など）はそのまま動かす
+func (w *World) stepInField(enemy *Enemy, dx, dy float64) (x, y float64) {
+	r := enemy.GetRadius()
+	x, y = enemy.x, enemy.y
+	if !w.nav.open(x+r, y+r) || w.nav.open(x+dx+r, y+dy+r) {
+		return x + dx, y + dy
+	}
+	if w.nav.open(x+dx+r, y+r) {
+		return x + dx, y
+	}
+	if w.nav.open(x+r, y+dy+r) {
+		return x, y + dy
+	}
+	return x, y
+}
+
+// enemyDirection は敵がターゲットに向かう向きの単位ベクトルを返す
+// 本拠地に向かう場合は本拠地への流れ場、自機を追いかける場合はその自機のいるマスへの流れ場に沿って進む
+// 同じマスにいる場合とたどり着けない場合はまっすぐ進む
+func (w *World) enemyDirection(enemy *Enemy, target Entity) (dx, dy float64) {
+	field := w.nav
+	if target != Entity(w.base) {
+		field = w.chaseField(cellIndex(target.GetX(), target.GetY()))
+	}
+	r := enemy.GetRadius()
+	if fx, fy, ok := field.direction(enemy.x+r, enemy.y+r); ok {
+		return fx, fy
+	}
+
+	dx = target.GetX() - enemy.x
+	dy = target.GetY() - enemy.y
+	dist := math.Sqrt(dx*dx + dy*dy)
+	// 速度を正規化
+	if dist > 0 {
+		dx /= dist
+		dy /= dist
+	}
+	return dx, dy
+}
+
+// chaseField はマス goal に向かう流れ場を返す。同じマスに向かう敵の間で使い回す
+func (w *World) chaseField(goal int) *flowField {
+	if f, ok := w.chase[goal]; ok {
+		return f
+	}
+	if w.chase == nil {
+		w.chase = map[int]*flowField{}
+	}
+	f := w.nav.towards(goal)
+	w.chase[goal] = f
+	return f
+}
+
+// navItem は経路探索の優先度付きキューの要素
+type navItem struct {
+	cell, dist int
+}
+
+// navQueue は距離の短い順に取り出せる優先度付きキュー
+// 距離が同じ場合は添字の小さい順に取り出すので、結果は常に同じになる
+type navQueue []navItem
+
+func (q navQueue) Len() int { return len(q) }
+func (q navQueue) Less(i, j int) bool {
+	if q[i].dist != q[j].dist {
+		return q[i].dist < q[j].dist
+	}
+	return q[i].cell < q[j].cell
+}
+func (q navQueue) Swap(i, j int) { q[i], q[j] = q[j], q[i] }
+func (q *navQueue) Push(x any)   { *q = append(*q, x.(navItem)) }
+func (q *navQueue) Pop() any {
+	old := *q
+	item := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return item
+}
@@ -0,0 +1,44 @@
+package sim
+
+import "testing"
+
+// block で部分的に計算し直した距離が、すべてを計算し直した結果と一致することを確かめる
+func TestFlowFieldBlockMatchesRebuild(t *testing.T) {
+	f := newFlowField(Stage{
+		Base:      BaseConfig{X: 600, Y: 440, HP: 20},
+		Obstacles: []Obstacle{{X: 128, Y: 256, Width: 320, Height: 32}},
+	})
+	r := newRNG(1)
+	for i := 0; i < 100; i++ {
+		c := r.Intn(len(f.blocked))
+		if f.goal[c] {
+			continue
+		}
+		f.block(c)
+
+		want := f.clone()
+		want.rebuild()
+		for c := range f.dist {
+			if f.dist[c] != want.dist[c] {
+				t.Fatalf("block %d: dist[%d] = %d, want %d", i, c, f.dist[c], want.dist[c])
+			}
+			// 距離が同じマスが複数ある場合は次に進むマスが違ってもよいが、最短経路の上になければならない
+			next := f.next[c]
+			if next == -1 {
+				if f.dist[c] != 0 && f.dist[c] != navUnreachable {
+					t.Fatalf("block %d: cell %d has no next cell", i, c)
+				}
+				continue
+			}
+			cost := -1
+			f.forEachNeighbor(c, func(n, nc int) {
+				if n == next {
+					cost = nc
+				}
+			})
+			if cost == -1 || f.dist[next]+cost != f.dist[c] {
+				t.Fatalf("block %d: next[%d] = %d is not on a shortest path", i, c, next)
+			}
+		}
+	}
+}
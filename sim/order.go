@@ -0,0 +1,66 @@
+package sim
+
+import "math"
+
+// OrderKind はユニットへの指示の種類
+type OrderKind string
+
+const (
+	OrderMove   OrderKind = "move"   // 移動先へ移動する（既定）
+	OrderPatrol OrderKind = "patrol" // 現在の位置と移動先の間を往復する
+	OrderHold   OrderKind = "hold"   // その場にとどまり、敵を追いかけない
+
+	// 攻撃対象の選び方を Order.Targeting に変える。今の指示はそのまま続ける
+	OrderTargeting OrderKind = "targeting"
+)
+
+// 複数のユニットに同じ移動先を指示したときのユニット同士の間隔
+const formationSpacing = 20
+
+// 待機中のユニットが敵を追いかけ始める距離の、武器の射程に対する倍率
+const chaseRangeFactor = 2
+
+// applyOrder は指示を受けたユニットの行動を更新する
+func (w *World) applyOrder(order Order) {
+	n := 0
+	for _, id := range order.Units {
+		// タワーは動かないので、攻撃対象の選び方だけを変えられる
+		if i, ok := w.towerIndex[id]; ok && order.Kind == OrderTargeting {
+			if _, ok := targetings[order.Targeting]; ok {
+				w.towers[i].targeting = order.Targeting
+			}
+			continue
+		}
+		i, ok := w.playerIndex[id]
+		if !ok {
+			continue
+		}
+		dx, dy := formationOffset(n, len(order.Units))
+		target := Point{X: order.Target.X + dx, Y: order.Target.Y + dy}
+		player := &w.players[i]
+		switch order.Kind {
+		case OrderPatrol:
+			player.patrolTo(target, order.Queue)
+		case OrderHold:
+			player.holdPosition()
+		case OrderTargeting:
+			if _, ok := targetings[order.Targeting]; ok {
+				player.targeting = order.Targeting
+			}
+		default:
+			player.moveTo(target, order.Queue)
+		}
+		n++
+	}
+}
+
+// formationOffset は count 体のユニットを移動先の周りに正方形に並べたときの、
+// n 番目のユニットの移動先からのずれを返す
+func formationOffset(n, count int) (dx, dy float64) {
+	cols := int(math.Ceil(math.Sqrt(float64(count))))
+	rows := (count + cols - 1) / cols
+	col, row := n%cols, n/cols
+	dx = (float64(col) - float64(cols-1)/2) * formationSpacing
+	dy = (float64(row) - float64(rows-1)/2) * formationSpacing
+	return dx, dy
+}
@@ -0,0 +1,70 @@
+package sim
+
+// スナップショットを保存する間隔（ティック数）
+const snapshotInterval = 300
+
+// Playback はリプレイを 1 ティックずつ再生する
+// 一定間隔でワールドのスナップショットを保存しておき、シークするときは
+// 直前のスナップショットから再シミュレーションする
+type Playback struct {
+	replay    *Replay
+	world     *World
+	snapshots []*World // snapshots[i] は i*snapshotInterval ティック目の開始時点の状態
+}
+
+func NewPlayback(stage Stage, replay *Replay) (*Playback, error) {
+	if stage.ID != replay.StageID {
+		return nil, fmtStageMismatch(stage, replay)
+	}
+	return &Playback{
+		replay: replay,
+		world:  NewWorld(stage, replay.Seed),
+	}, nil
+}
+
+// World は再生中のワールドを返す。シークするとワールドは別のものに置き換わる
+func (p *Playback) World() *World {
+	return p.world
+}
+
+// Tick は再生済みのティック数を返す
+func (p *Playback) Tick() int {
+	return p.world.Tick()
+}
+
+// Ticks はリプレイ全体のティック数を返す
+func (p *Playback) Ticks() int {
+	return p.replay.Ticks
+}
+
+// Done は最後まで再生し終えたかを返す
+func (p *Playback) Done() bool {
+	return p.world.Tick() >= p.replay.Ticks || p.world.Status() != Running
+}
+
+// Step は 1 ティック分再生する
+func (p *Playback) Step() {
+	if p.Done() {
+		return
+	}
+	tick := p.world.Tick()
+	if tick%snapshotInterval == 0 && tick/snapshotInterval == len(p.snapshots) {
+		p.snapshots = append(p.snapshots, p.world.clone())
+	}
+	p.world.Step(p.replay.InputAt(tick))
+}
+
+// Seek は tick ティック目まで再生した状態に移動する
+func (p *Playback) Seek(tick int) {
+	tick = clamp(tick, 0, p.replay.Ticks)
+
+	// 巻き戻す場合と、先のスナップショットがある場合はスナップショットから再開する
+	if i := min(tick/snapshotInterval, len(p.snapshots)-1); i >= 0 {
+		if tick < p.world.Tick() || i*snapshotInterval > p.world.Tick() {
+			p.world = p.snapshots[i].clone()
+		}
+	}
+	for p.world.Tick() < tick && !p.Done() {
+		p.Step()
+	}
+}
@@ -0,0 +1,44 @@
+package sim
+
+import "testing"
+
+// シークで前後に移動した結果が、最初から順に再生した結果と一致することを確かめる
+func TestPlaybackSeekMatchesLinearPlay(t *testing.T) {
+	stage, err := LoadDefaultStage("sample")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, replay := record(t, stage, 3)
+
+	// 各ティックまで順に再生したときの状態
+	linear := NewWorld(stage, replay.Seed)
+	want := []string{summary(linear)}
+	for tick := 0; tick < replay.Ticks; tick++ {
+		linear.Step(replay.InputAt(tick))
+		want = append(want, summary(linear))
+	}
+
+	p, err := NewPlayback(stage, replay)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tick := range []int{100, snapshotInterval*2 + 17, 5, replay.Ticks, snapshotInterval, 0, replay.Ticks / 2, replay.Ticks + 100, -10} {
+		p.Seek(tick)
+		tick = clamp(tick, 0, replay.Ticks)
+		if got := p.Tick(); got != tick {
+			t.Fatalf("Seek(%d): tick = %d", tick, got)
+		}
+		if got := summary(p.World()); got != want[tick] {
+			t.Errorf("Seek(%d) differs from linear play:\n%s\nwant:\n%s", tick, got, want[tick])
+		}
+	}
+
+	// シークした後に 1 ティックずつ再生しても一致する
+	p.Seek(snapshotInterval - 3)
+	for i := 0; i < 10; i++ {
+		p.Step()
+	}
+	if got := summary(p.World()); got != want[snapshotInterval+7] {
+		t.Errorf("stepping after Seek differs from linear play:\n%s\nwant:\n%s", got, want[snapshotInterval+7])
+	}
+}
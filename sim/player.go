@@ -0,0 +1,221 @@
+package sim
+
+import (
+	"math"
+)
+
+// プレイヤーユニットの最大 HP
+const playerMaxHP = 10
+
+type Player struct {
+	id     EntityID
+	x, y   float64
+	speed  float64
+	weapon Weapon
+	HP     int
+
+	// 攻撃対象の選び方の名前。RegisterTargeting で登録したもの
+	targeting string
+
+	// 順に向かう経由地（ユニットの左上の座標）。空の場合は待機している
+	waypoints []Point
+	// true の場合、到着した経由地を後ろに回して経由地を巡回し続ける
+	patrolling bool
+	// true の場合、待機中に近くの敵を追いかけない
+	holding bool
+	// 待機している場所。敵を追いかけた後はここに戻る
+	postX, postY float64
+
+	effects statusEffects
+
+	framesSinceLastBullet int
+	respawnTimer          int // 倒されてから復活するまでの残りフレーム数
+}
+
+func NewPlayer() Player {
+	return Player{
+		x:         FieldWidth / 2,
+		y:         FieldHeight / 2, // 情報表示領域を除いた領域の中央に配置
+		postX:     FieldWidth / 2,
+		postY:     FieldHeight / 2,
+		speed:     4,
+		HP:        playerMaxHP,
+		weapon:    unitWeapons.Player,
+		targeting: DefaultTargeting,
+	}
+}
+
+// waypoint はユニットの中央が (x, y) に重なるときのユニットの左上の座標を返す
+func (p *Player) waypoint(target Point) Point {
+	return Point{X: target.X - p.GetRadius(), Y: target.Y - p.GetRadius()}
+}
+
+// moveTo はユニットを target へ移動させる。queue が true の場合は経由地として後ろに追加する
+func (p *Player) moveTo(target Point, queue bool) {
+	if !queue || p.patrolling {
+		p.waypoints = p.waypoints[:0]
+		p.patrolling = false
+	}
+	p.holding = false
+	p.waypoints = append(p.waypoints, p.waypoint(target))
+}
+
+// patrolTo は今の位置（queue が true の場合は最後の経由地）と target の間を往復させる
+func (p *Player) patrolTo(target Point, queue bool) {
+	from := Point{X: p.x, Y: p.y}
+	if queue && len(p.waypoints) > 0 {
+		from = p.waypoints[len(p.waypoints)-1]
+	} else {
+		p.waypoints = p.waypoints[:0]
+	}
+	p.waypoints = append(p.waypoints, p.waypoint(target), from)
+	p.patrolling = true
+	p.holding = false
+}
+
+// holdPosition はユニットをその場にとどまらせる
+func (p *Player) holdPosition() {
+	p.waypoints = p.waypoints[:0]
+	p.patrolling = false
+	p.holding = true
+	p.postX, p.postY = p.x, p.y
+}
+
+func (p *Player) Update(w *World) {
+	p.framesSinceLastBullet++
+	p.effects.update(w, p)
+	if p.effects.stunned() {
+		return
+	}
+
+	if len(p.waypoints) > 0 {
+		next := p.waypoints[0]
+		if !p.step(next.X, next.Y) {
+			return
+		}
+		// 経由地に到着した
+		p.waypoints = append(p.waypoints[:0], p.waypoints[1:]...)
+		if p.patrolling {
+			p.waypoints = append(p.waypoints, next)
+		}
+		if len(p.waypoints) == 0 {
+			p.postX, p.postY = p.x, p.y
+		}
+		return
+	}
+	if p.holding {
+		return
+	}
+
+	// 待機中は待機場所の近くに来た敵を射程に入るまで追いかけ、いなくなったら待機場所に戻る
+	chaseRange := p.weapon.Range * chaseRangeFactor
+	enemy := w.nearestEnemyTo(p.postX, p.postY, chaseRange, func(Entity) bool { return false })
+	if enemy == nil {
+		p.step(p.postX, p.postY)
+		return
+	}
+	dx, dy := enemy.x-p.x, enemy.y-p.y
+	if !p.weapon.inRange(dx, dy) {
+		p.step(enemy.x, enemy.y)
+	}
+}
+
+// step は (x, y) に向かって 1 ティック分移動する。到着した場合は true を返す
+func (p *Player) step(x, y float64) bool {
+	dx := x - p.x
+	dy := y - p.y
+	distance := math.Sqrt(dx*dx + dy*dy)
+
+	speed := p.speed * p.effects.speedMultiplier()
+	if distance > speed {
+		ratio := speed / distance
+		p.x += dx * ratio
+		p.y += dy * ratio
+		return false
+	}
+	p.x, p.y = x, y
+	return true
+}
+
+func (p *Player) IsHit(bulletX, bulletY float64) bool {
+	radius := p.GetRadius()
+
+	// ユニットと弾の中心間の距離を計算
+	dx := p.x + radius - bulletX
+	dy := p.y + radius - bulletY
+	distance := math.Sqrt(dx*dx + dy*dy)
+
+	// 2つの円の半径の合計よりも距離が小さい場合、当たりと判定
+	return distance < (radius + bulletRadius)
+}
+
+// MaxHP はユニットの最大 HP を返す
+func (p *Player) MaxHP() int {
+	return playerMaxHP
+}
+
+func (p *Player) ID() EntityID {
+	return p.id
+}
+
+func (p *Player) GetX() float64 {
+	return p.x
+}
+
+func (p *Player) GetY() float64 {
+	return p.y
+}
+
+func (p *Player) GetPosition() (x, y int) {
+	return int(p.x), int(p.y)
+}
+
+func (p *Player) GetRadius() float64 {
+	return 8
+}
+
+func (p *Player) GetSize() (width, height int) {
+	radius := p.GetRadius()
+	return int(radius * 2), int(radius * 2)
+}
+
+// Weapon はプレイヤーユニットが装備している武器を返す
+func (p *Player) Weapon() Weapon {
+	return p.weapon
+}
+
+// Waypoints はユニットがこれから向かう経由地を、ユニットの中央の座標で返す
+func (p *Player) Waypoints() []Point {
+	points := make([]Point, len(p.waypoints))
+	for i, wp := range p.waypoints {
+		points[i] = Point{X: wp.X + p.GetRadius(), Y: wp.Y + p.GetRadius()}
+	}
+	return points
+}
+
+// Effects はユニットが受けている状態効果の名前を受けた順に返す
+func (p *Player) Effects() []EffectID {
+	return p.effects.ids()
+}
+
+func (p *Player) statusEffects() *statusEffects {
+	return &p.effects
+}
+
+// Targeting はユニットの攻撃対象の選び方の名前を返す
+func (p *Player) Targeting() string {
+	return p.targeting
+}
+
+// OrderKind はユニットが今従っている指示の種類を返す。指示がなく待機している場合は空文字列を返す
+func (p *Player) OrderKind() OrderKind {
+	switch {
+	case p.patrolling:
+		return OrderPatrol
+	case p.holding:
+		return OrderHold
+	case len(p.waypoints) > 0:
+		return OrderMove
+	}
+	return ""
+}
@@ -0,0 +1,29 @@
+package sim
+
+// rng はシミュレーション用の疑似乱数生成器 (SplitMix64)
+// 状態が値 1 つだけなので、ワールドを複製すると乱数列もそのまま複製される
+type rng struct {
+	state uint64
+}
+
+func newRNG(seed int64) rng {
+	return rng{state: uint64(seed)}
+}
+
+func (r *rng) uint64() uint64 {
+	r.state += 0x9e3779b97f4a7c15
+	z := r.state
+	z = (z ^ (z >> 30)) * 0xbf58476d1ce4e5b9
+	z = (z ^ (z >> 27)) * 0x94d049bb133111eb
+	return z ^ (z >> 31)
+}
+
+// Float64 は [0, 1) の乱数を返す
+func (r *rng) Float64() float64 {
+	return float64(r.uint64()>>11) / (1 << 53)
+}
+
+// Intn は [0, n) の乱数を返す。n は正でなければならない
+func (r *rng) Intn(n int) int {
+	return int(r.uint64() % uint64(n))
+}
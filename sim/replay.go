@@ -0,0 +1,106 @@
+package sim
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+)
+
+// ReplayFormatVersion は読み込み可能なリプレイファイルのフォーマットのバージョン
+const ReplayFormatVersion = 2
+
+// TickInput はあるティックで与えられた入力
+type TickInput struct {
+	Tick  int   `json:"tick"`
+	Input Input `json:"input"`
+}
+
+// Replay はプレイを再現するための記録
+// 同じステージとシードから始めて、各ティックに同じ入力を与えるとプレイが再現される
+type Replay struct {
+	Version int         `json:"version"`
+	StageID string      `json:"stage_id"`
+	Seed    int64       `json:"seed"`
+	Ticks   int         `json:"ticks"`  // 記録したティック数
+	Inputs  []TickInput `json:"inputs"` // 操作があったティックの入力。ティックの昇順に並ぶ
+}
+
+// InputAt は tick 番目（0 始まり）の Step に与える入力を返す
+func (r *Replay) InputAt(tick int) Input {
+	i := sort.Search(len(r.Inputs), func(i int) bool { return r.Inputs[i].Tick >= tick })
+	if i < len(r.Inputs) && r.Inputs[i].Tick == tick {
+		return r.Inputs[i].Input
+	}
+	return Input{}
+}
+
+// Encode はリプレイを JSON 形式で書き出す
+func (r *Replay) Encode(w io.Writer) error {
+	return json.NewEncoder(w).Encode(r)
+}
+
+// LoadReplay は JSON 形式のリプレイを読み込む
+func LoadReplay(r io.Reader) (*Replay, error) {
+	var replay Replay
+	if err := json.NewDecoder(r).Decode(&replay); err != nil {
+		return nil, fmt.Errorf("failed to decode replay: %w", err)
+	}
+	if replay.Version != ReplayFormatVersion {
+		return nil, fmt.Errorf("unsupported replay format version %d (want %d)", replay.Version, ReplayFormatVersion)
+	}
+	for i := 1; i < len(replay.Inputs); i++ {
+		if replay.Inputs[i-1].Tick >= replay.Inputs[i].Tick {
+			return nil, fmt.Errorf("replay inputs are not sorted by tick at index %d", i)
+		}
+	}
+	return &replay, nil
+}
+
+// Recorder はプレイ中の入力をティックごとに記録する
+type Recorder struct {
+	replay Replay
+}
+
+func NewRecorder(stageID string, seed int64) *Recorder {
+	return &Recorder{
+		replay: Replay{
+			Version: ReplayFormatVersion,
+			StageID: stageID,
+			Seed:    seed,
+		},
+	}
+}
+
+// Record は tick 番目（0 始まり）の Step に与えた入力を記録する
+// 操作がなかったティックは記録を省略する
+func (r *Recorder) Record(tick int, in Input) {
+	if tick+1 > r.replay.Ticks {
+		r.replay.Ticks = tick + 1
+	}
+	if in.empty() {
+		return
+	}
+	r.replay.Inputs = append(r.replay.Inputs, TickInput{Tick: tick, Input: in})
+}
+
+// Replay はここまでに記録した内容を返す
+func (r *Recorder) Replay() *Replay {
+	return &r.replay
+}
+
+// Simulate はリプレイの記録どおりにステージを最後まで進めたワールドを返す
+func Simulate(stage Stage, replay *Replay) (*World, error) {
+	if stage.ID != replay.StageID {
+		return nil, fmtStageMismatch(stage, replay)
+	}
+	w := NewWorld(stage, replay.Seed)
+	for tick := 0; tick < replay.Ticks; tick++ {
+		w.Step(replay.InputAt(tick))
+	}
+	return w, nil
+}
+
+func fmtStageMismatch(stage Stage, replay *Replay) error {
+	return fmt.Errorf("replay is for stage %q, not %q", replay.StageID, stage.ID)
+}
@@ -0,0 +1,62 @@
+package sim
+
+import (
+	"bytes"
+	"testing"
+)
+
+// 記録したリプレイを書き出して読み込み直し、Simulate で進めた結果が記録したプレイと一致することを確かめる
+func TestSimulateReproducesRecordedPlay(t *testing.T) {
+	stages, err := DefaultStages()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, stage := range stages {
+		t.Run(stage.ID, func(t *testing.T) {
+			live, replay := record(t, stage, 42)
+
+			var buf bytes.Buffer
+			if err := replay.Encode(&buf); err != nil {
+				t.Fatal(err)
+			}
+			loaded, err := LoadReplay(&buf)
+			if err != nil {
+				t.Fatal(err)
+			}
+			w, err := Simulate(stage, loaded)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got, want := summary(w), summary(live); got != want {
+				t.Errorf("simulated world differs from the recorded play:\n%s\nwant:\n%s", got, want)
+			}
+		})
+	}
+}
+
+func TestSimulateRejectsOtherStage(t *testing.T) {
+	stage, err := LoadDefaultStage("sample")
+	if err != nil {
+		t.Fatal(err)
+	}
+	replay := NewRecorder("debug", 1).Replay()
+	if _, err := Simulate(stage, replay); err == nil {
+		t.Error("Simulate accepted a replay for another stage")
+	}
+}
+
+func TestLoadReplayErrors(t *testing.T) {
+	for _, tt := range []struct {
+		name, json string
+	}{
+		{"broken", `{`},
+		{"old version", `{"version": 1, "stage_id": "sample"}`},
+		{"unsorted inputs", `{"version": 2, "stage_id": "sample", "inputs": [{"tick": 5}, {"tick": 3}]}`},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := LoadReplay(bytes.NewBufferString(tt.json)); err == nil {
+				t.Error("LoadReplay succeeded")
+			}
+		})
+	}
+}
@@ -0,0 +1,87 @@
+package sim
+
+// 倒されたプレイヤーユニットが本拠地で復活するまでのフレーム数
+const respawnFrames = 10 * 60
+
+// 復活を早めるときに、残り 1 秒あたりに払うお金
+const respawnCostPerSecond = 5
+
+// removeFallenPlayers は HP がなくなったプレイヤーユニットをワールドから取り除き、復活待ちにする
+func (w *World) removeFallenPlayers() {
+	alive := w.players[:0]
+	removed := false
+	for _, player := range w.players {
+		if player.HP > 0 {
+			alive = append(alive, player)
+			continue
+		}
+		player.respawnTimer = respawnFrames
+		w.fallen = append(w.fallen, player)
+		removed = true
+	}
+	w.players = alive
+	if removed {
+		w.reindexPlayers()
+	}
+}
+
+// updateRespawns は復活待ちの時間を進め、時間になったユニットを復活させる
+func (w *World) updateRespawns() {
+	for i := range w.fallen {
+		w.fallen[i].respawnTimer--
+	}
+	for len(w.fallen) > 0 && w.fallen[0].respawnTimer <= 0 {
+		w.respawn()
+	}
+}
+
+// respawn は最も早く復活するユニットを本拠地で復活させる
+// 倒される前と同じ ID と攻撃対象の選び方のまま復活し、それ以外の指示は取り消される
+func (w *World) respawn() {
+	player := w.fallen[0]
+	w.fallen = append(w.fallen[:0], w.fallen[1:]...)
+
+	player.x = w.base.x + w.base.GetRadius() - player.GetRadius()
+	player.y = w.base.y + w.base.GetRadius() - player.GetRadius()
+	player.postX, player.postY = player.x, player.y
+	player.waypoints = nil
+	player.patrolling = false
+	player.holding = false
+	player.HP = playerMaxHP
+	player.effects = nil
+	player.respawnTimer = 0
+	player.framesSinceLastBullet = 0
+
+	w.playerIndex[player.id] = len(w.players)
+	w.players = append(w.players, player)
+}
+
+// RespawnTimers は復活待ちのユニットが復活するまでの残りフレーム数を、早い順に返す
+func (w *World) RespawnTimers() []int {
+	timers := make([]int, len(w.fallen))
+	for i, player := range w.fallen {
+		timers[i] = player.respawnTimer
+	}
+	return timers
+}
+
+// RespawnCost は最も早く復活するユニットをすぐに復活させるのに必要なお金を返す
+// 復活待ちのユニットがいない場合は 0 を返す
+func (w *World) RespawnCost() int {
+	if len(w.fallen) == 0 {
+		return 0
+	}
+	seconds := (w.fallen[0].respawnTimer + 59) / 60
+	return seconds * respawnCostPerSecond
+}
+
+// hastenRespawn はお金を払って、最も早く復活するユニットをすぐに復活させる
+func (w *World) hastenRespawn() {
+	if len(w.fallen) == 0 {
+		return
+	}
+	if cost := w.RespawnCost(); w.money >= cost {
+		w.money -= cost
+		w.respawn()
+	}
+}
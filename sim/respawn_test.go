@@ -0,0 +1,76 @@
+package sim
+
+import (
+	"reflect"
+	"testing"
+)
+
+// newRespawnWorld は最初のプレイヤーユニットを倒したワールドを作り、そのユニットの ID を返す
+func newRespawnWorld(t *testing.T) (*World, EntityID) {
+	t.Helper()
+	w := newCombatWorld()
+	w.addPlayer(NewPlayer())
+	player := &w.players[0]
+	player.effects.apply(EffectSlow)
+	player.holding = true
+	player.HP = 0
+	w.Step(Input{})
+	if len(w.Players()) != 0 || !reflect.DeepEqual(w.RespawnTimers(), []int{respawnFrames}) {
+		t.Fatalf("players = %d, respawn timers = %v after the player fell", len(w.Players()), w.RespawnTimers())
+	}
+	return w, w.lastID
+}
+
+// 倒されたユニットは respawnFrames 後に、同じ ID のまま本拠地で復活する
+func TestPlayerRespawnsAtBase(t *testing.T) {
+	w, id := newRespawnWorld(t)
+	for i := 0; i < respawnFrames-1; i++ {
+		w.Step(Input{})
+	}
+	if w.Entity(id) != nil {
+		t.Fatal("player respawned a tick early")
+	}
+	w.Step(Input{})
+	player, ok := w.Entity(id).(*Player)
+	if !ok {
+		t.Fatalf("player did not respawn after %d ticks", respawnFrames)
+	}
+	if player.HP != playerMaxHP || len(player.Effects()) != 0 || player.holding {
+		t.Errorf("respawned player hp = %d, effects = %v, holding = %t", player.HP, player.Effects(), player.holding)
+	}
+	cx, cy := player.x+player.GetRadius(), player.y+player.GetRadius()
+	if bx, by := w.base.x+w.base.GetRadius(), w.base.y+w.base.GetRadius(); cx != bx || cy != by {
+		t.Errorf("respawned at (%g, %g), want the base center (%g, %g)", cx, cy, bx, by)
+	}
+}
+
+// 復活を早めるには、残りの秒数（切り上げ）に応じたお金が必要
+func TestHastenRespawnCost(t *testing.T) {
+	w, id := newRespawnWorld(t)
+	if got, want := w.RespawnCost(), respawnFrames/60*respawnCostPerSecond; got != want {
+		t.Errorf("cost = %d, want %d", got, want)
+	}
+	for i := 0; i < 61; i++ {
+		w.Step(Input{})
+	}
+	// 残り 539 フレームは 9 秒に切り上げる
+	cost := 9 * respawnCostPerSecond
+	if got := w.RespawnCost(); got != cost {
+		t.Errorf("cost after 61 ticks = %d, want %d", got, cost)
+	}
+
+	hasten := Input{Commands: []Command{CommandHastenRespawn}}
+	w.money = cost - 1
+	w.Step(hasten)
+	if w.Entity(id) != nil || w.Money() != cost-1 {
+		t.Fatalf("hastened without enough money (money = %d)", w.Money())
+	}
+	w.money = cost
+	w.Step(hasten)
+	if w.Entity(id) == nil || w.Money() != 0 {
+		t.Errorf("player respawned = %t, money = %d after hastening, want true, 0", w.Entity(id) != nil, w.Money())
+	}
+	if w.RespawnCost() != 0 {
+		t.Errorf("cost with nobody waiting = %d, want 0", w.RespawnCost())
+	}
+}
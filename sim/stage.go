@@ -0,0 +1,189 @@
+package sim
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"sort"
+)
+
+// StageFormatVersion は読み込み可能なステージファイルのフォーマットのバージョン
+const StageFormatVersion = 1
+
+// ゲームに同梱するステージファイル
+//
+//go:embed stages/*.json
+var stageFiles embed.FS
+
+// LoadStage は JSON 形式のステージファイルを読み込み、内容を検証する
+func LoadStage(r io.Reader) (Stage, error) {
+	var stage Stage
+	dec := json.NewDecoder(r)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&stage); err != nil {
+		return Stage{}, fmt.Errorf("failed to decode stage: %w", err)
+	}
+	if err := stage.Validate(); err != nil {
+		return Stage{}, err
+	}
+	return stage, nil
+}
+
+// LoadStageFile は指定したパスのステージファイルを読み込む
+func LoadStageFile(name string) (Stage, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return Stage{}, err
+	}
+	defer f.Close()
+
+	stage, err := LoadStage(f)
+	if err != nil {
+		return Stage{}, fmt.Errorf("%s: %w", name, err)
+	}
+	return stage, nil
+}
+
+// DefaultStages は同梱されているステージをファイル名順に返す
+func DefaultStages() ([]Stage, error) {
+	entries, err := stageFiles.ReadDir("stages")
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name() < entries[j].Name() })
+
+	stages := make([]Stage, 0, len(entries))
+	for _, entry := range entries {
+		name := path.Join("stages", entry.Name())
+		f, err := stageFiles.Open(name)
+		if err != nil {
+			return nil, err
+		}
+		stage, err := LoadStage(f)
+		f.Close()
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+		stages = append(stages, stage)
+	}
+	return stages, nil
+}
+
+// LoadDefaultStage は同梱されているステージの中から ID が一致するものを返す
+func LoadDefaultStage(id string) (Stage, error) {
+	stages, err := DefaultStages()
+	if err != nil {
+		return Stage{}, err
+	}
+	for _, stage := range stages {
+		if stage.ID == id {
+			return stage, nil
+		}
+	}
+	return Stage{}, fmt.Errorf("stage %q not found", id)
+}
+
+// Validate はステージの内容がシミュレーションで扱える値になっているかを検証する
+func (s *Stage) Validate() error {
+	if s.Version != StageFormatVersion {
+		return fmt.Errorf("unsupported stage format version %d (want %d)", s.Version, StageFormatVersion)
+	}
+	if s.ID == "" {
+		return fmt.Errorf("stage id is empty")
+	}
+	if !inField(s.Base.X, s.Base.Y) {
+		return fmt.Errorf("base position (%g, %g) is out of the field", s.Base.X, s.Base.Y)
+	}
+	if s.Base.HP <= 0 {
+		return fmt.Errorf("base hp must be positive: %d", s.Base.HP)
+	}
+	if s.StartingMoney < 0 {
+		return fmt.Errorf("starting money must not be negative: %d", s.StartingMoney)
+	}
+	if s.LoseConditions.MaxReachedEnemies < 0 {
+		return fmt.Errorf("max reached enemies must not be negative: %d", s.LoseConditions.MaxReachedEnemies)
+	}
+	if zone := s.LoseConditions.LeakZone; zone != nil {
+		if zone.Width <= 0 || zone.Height <= 0 {
+			return fmt.Errorf("leak zone: size must be positive: %gx%g", zone.Width, zone.Height)
+		}
+		if !inField(zone.X, zone.Y) || !inField(zone.X+zone.Width, zone.Y+zone.Height) {
+			return fmt.Errorf("leak zone is out of the field")
+		}
+	}
+	if len(s.Waves) == 0 && s.Endless == nil {
+		return fmt.Errorf("stage has no waves")
+	}
+	for i, wave := range s.Waves {
+		if wave.TotalFrames <= 0 {
+			return fmt.Errorf("wave %d: total frames must be positive: %d", i, wave.TotalFrames)
+		}
+		for j, spawn := range wave.EnemySpawns {
+			if spawn.SpawnFrame < 0 || spawn.SpawnFrame >= wave.TotalFrames {
+				return fmt.Errorf("wave %d, spawn %d: spawn frame %d is out of range [0, %d)", i, j, spawn.SpawnFrame, wave.TotalFrames)
+			}
+			if _, ok := archetypes.Lookup(spawn.Enemy); !ok {
+				return fmt.Errorf("wave %d, spawn %d: unknown enemy %q", i, j, spawn.Enemy)
+			}
+			if spawn.Count < 0 {
+				return fmt.Errorf("wave %d, spawn %d: count must not be negative: %d", i, j, spawn.Count)
+			}
+			if spawn.Interval < 0 {
+				return fmt.Errorf("wave %d, spawn %d: interval must not be negative: %d", i, j, spawn.Interval)
+			}
+			if spawn.Jitter < 0 {
+				return fmt.Errorf("wave %d, spawn %d: jitter must not be negative: %g", i, j, spawn.Jitter)
+			}
+			if spawn.lastSpawnFrame() >= wave.TotalFrames {
+				return fmt.Errorf("wave %d, spawn %d: last enemy spawns at frame %d, after the wave ends", i, j, spawn.lastSpawnFrame())
+			}
+			switch spawn.Edge {
+			case "":
+				if !inField(spawn.X, spawn.Y) {
+					return fmt.Errorf("wave %d, spawn %d: position (%g, %g) is out of the field", i, j, spawn.X, spawn.Y)
+				}
+			case EdgeTop, EdgeBottom, EdgeLeft, EdgeRight:
+			default:
+				return fmt.Errorf("wave %d, spawn %d: unknown edge %q", i, j, spawn.Edge)
+			}
+		}
+	}
+
+	base := NewBase(s.Base)
+	size := base.GetRadius() * 2
+	for i, obstacle := range s.Obstacles {
+		if obstacle.Width <= 0 || obstacle.Height <= 0 {
+			return fmt.Errorf("obstacle %d: size must be positive: %gx%g", i, obstacle.Width, obstacle.Height)
+		}
+		if !inField(obstacle.X, obstacle.Y) || !inField(obstacle.X+obstacle.Width, obstacle.Y+obstacle.Height) {
+			return fmt.Errorf("obstacle %d is out of the field", i)
+		}
+		if obstacle.overlaps(base.x, base.y, size, size) {
+			return fmt.Errorf("obstacle %d overlaps the base", i)
+		}
+	}
+	// 障害物で塞がれて本拠地にたどり着けない出現位置がないか確認する
+	nav := newFlowField(*s)
+	for i, wave := range s.Waves {
+		for j, spawn := range wave.EnemySpawns {
+			for n := 0; n < spawn.count(); n++ {
+				if x, y := spawn.position(n); !nav.reachable(x, y) {
+					return fmt.Errorf("wave %d, spawn %d: position (%g, %g) cannot reach the base", i, j, x, y)
+				}
+			}
+		}
+	}
+	if s.Endless != nil {
+		if err := s.Endless.validate(nav); err != nil {
+			return fmt.Errorf("endless: %w", err)
+		}
+	}
+	return nil
+}
+
+func inField(x, y float64) bool {
+	return x >= 0 && x <= FieldWidth && y >= 0 && y <= FieldHeight
+}
@@ -0,0 +1,67 @@
+package sim
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestStageValidateErrors(t *testing.T) {
+	for _, tt := range []struct {
+		name   string
+		modify func(s *Stage)
+		want   string
+	}{
+		{"version", func(s *Stage) { s.Version = 0 }, "unsupported stage format version"},
+		{"empty id", func(s *Stage) { s.ID = "" }, "stage id is empty"},
+		{"base out of field", func(s *Stage) { s.Base.X = FieldWidth + 1 }, "base position"},
+		{"base hp", func(s *Stage) { s.Base.HP = 0 }, "base hp must be positive"},
+		{"negative money", func(s *Stage) { s.StartingMoney = -1 }, "starting money"},
+		{"negative leaks", func(s *Stage) { s.LoseConditions.MaxReachedEnemies = -1 }, "max reached enemies"},
+		{"leak zone size", func(s *Stage) { s.LoseConditions.LeakZone = &Obstacle{X: 10, Y: 10} }, "leak zone: size must be positive"},
+		{"leak zone out of field", func(s *Stage) {
+			s.LoseConditions.LeakZone = &Obstacle{X: FieldWidth - 10, Y: 0, Width: 20, Height: 20}
+		}, "leak zone is out of the field"},
+		{"no waves", func(s *Stage) { s.Waves = nil }, "stage has no waves"},
+		{"wave frames", func(s *Stage) { s.Waves[0].TotalFrames = 0 }, "total frames must be positive"},
+		{"spawn frame", func(s *Stage) { s.Waves[0].EnemySpawns[0].SpawnFrame = s.Waves[0].TotalFrames }, "spawn frame"},
+		{"unknown enemy", func(s *Stage) { s.Waves[0].EnemySpawns[0].Enemy = "nobody" }, `unknown enemy "nobody"`},
+		{"spawn after wave", func(s *Stage) {
+			s.Waves[0].EnemySpawns[0].Count = 10
+			s.Waves[0].EnemySpawns[0].Interval = 100
+		}, "after the wave ends"},
+		{"spawn out of field", func(s *Stage) { s.Waves[0].EnemySpawns[0].Y = -1 }, "out of the field"},
+		{"unknown edge", func(s *Stage) { s.Waves[0].EnemySpawns[0].Edge = "middle" }, `unknown edge "middle"`},
+		{"obstacle size", func(s *Stage) { s.Obstacles = []Obstacle{{X: 10, Y: 10}} }, "size must be positive"},
+		{"obstacle over base", func(s *Stage) {
+			s.Obstacles = []Obstacle{{X: s.Base.X, Y: s.Base.Y, Width: 10, Height: 10}}
+		}, "overlaps the base"},
+		{"spawn walled in", func(s *Stage) {
+			s.Obstacles = []Obstacle{{X: 0, Y: 64, Width: 64, Height: 32}, {X: 64, Y: 0, Width: 32, Height: 96}}
+		}, "cannot reach the base"},
+		{"endless budget", func(s *Stage) {
+			s.Endless = &EndlessConfig{WaveFrames: 600, SpawnInterval: 30, Edges: []string{EdgeTop}, Enemies: []EndlessEnemy{{Enemy: "a", Cost: 1}}}
+		}, "endless: budget must be positive"},
+		{"endless boss", func(s *Stage) {
+			s.Endless = &EndlessConfig{Budget: 1, WaveFrames: 600, SpawnInterval: 30, Edges: []string{EdgeTop}, Enemies: []EndlessEnemy{{Enemy: "a", Cost: 1}}, BossEvery: 5}
+		}, `endless: unknown boss ""`},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			stage, err := LoadDefaultStage("sample")
+			if err != nil {
+				t.Fatal(err)
+			}
+			tt.modify(&stage)
+			err = stage.Validate()
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Validate() = %v, want an error containing %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadStageRejectsUnknownFields(t *testing.T) {
+	_, err := LoadStage(strings.NewReader(`{"version": 1, "id": "x", "wavez": []}`))
+	if err == nil || !strings.Contains(err.Error(), "wavez") {
+		t.Errorf("LoadStage() = %v, want an error about the unknown field", err)
+	}
+}
@@ -0,0 +1,103 @@
+package sim
+
+import (
+	"fmt"
+	"math"
+)
+
+// Targeting は攻撃範囲内の敵から攻撃する敵を選ぶ方法
+// 独自の方法を使う場合は、Targeting を実装して RegisterTargeting で登録する
+type Targeting interface {
+	// Score は (x, y) にいるユニットから見た enemy の優先度を返す
+	// 範囲内の敵のうち優先度が最も高いものを攻撃する
+	Score(w *World, x, y float64, enemy *Enemy) float64
+}
+
+// TargetingFunc は関数を Targeting として使うためのアダプタ
+type TargetingFunc func(w *World, x, y float64, enemy *Enemy) float64
+
+func (f TargetingFunc) Score(w *World, x, y float64, enemy *Enemy) float64 {
+	return f(w, x, y, enemy)
+}
+
+// 組み込みの攻撃対象の選び方
+const (
+	TargetNearest   = "nearest"   // 最も近い敵
+	TargetFirst     = "first"     // 本拠地に最も近い敵
+	TargetStrongest = "strongest" // HP が最も高い敵
+	TargetWeakest   = "weakest"   // HP が最も低い敵
+	TargetReward    = "reward"    // 倒したときに得られるお金が最も多い敵
+)
+
+// DefaultTargeting はユニットが最初に使う攻撃対象の選び方
+const DefaultTargeting = TargetNearest
+
+var (
+	targetings     = map[string]Targeting{}
+	targetingNames []string // 登録順
+)
+
+func init() {
+	RegisterTargeting(TargetNearest, TargetingFunc(func(w *World, x, y float64, enemy *Enemy) float64 {
+		return -math.Hypot(enemy.x-x, enemy.y-y)
+	}))
+	RegisterTargeting(TargetFirst, TargetingFunc(func(w *World, x, y float64, enemy *Enemy) float64 {
+		// 障害物を迂回する敵もいるので、直線距離ではなく経路に沿った本拠地までの距離で比べる
+		// 同じマスにいる敵の間では、本拠地までの直線距離が近いほうを優先する
+		// 経路の距離は 1 マスで 10 違うので、1000 倍すればフィールド内の直線距離の差より常に大きくなる
+		r := enemy.GetRadius()
+		dist := w.nav.dist[cellIndex(enemy.x+r, enemy.y+r)]
+		return -float64(dist)*1000 - math.Hypot(enemy.x-w.base.x, enemy.y-w.base.y)
+	}))
+	RegisterTargeting(TargetStrongest, TargetingFunc(func(w *World, x, y float64, enemy *Enemy) float64 {
+		return float64(enemy.HP)
+	}))
+	RegisterTargeting(TargetWeakest, TargetingFunc(func(w *World, x, y float64, enemy *Enemy) float64 {
+		return -float64(enemy.HP)
+	}))
+	RegisterTargeting(TargetReward, TargetingFunc(func(w *World, x, y float64, enemy *Enemy) float64 {
+		return float64(enemy.archetype.Reward)
+	}))
+}
+
+// RegisterTargeting は攻撃対象の選び方を name という名前で登録する
+// リプレイには名前だけが記録されるので、再生する側でも同じ名前で同じものを登録しておく必要がある
+// 同じ名前を二度登録すると panic する
+func RegisterTargeting(name string, t Targeting) {
+	if _, ok := targetings[name]; ok {
+		panic(fmt.Sprintf("targeting %q is already registered", name))
+	}
+	targetings[name] = t
+	targetingNames = append(targetingNames, name)
+}
+
+// Targetings は登録されている攻撃対象の選び方の名前を登録順に返す
+func Targetings() []string {
+	return append([]string(nil), targetingNames...)
+}
+
+// lookupTargeting は名前に対応する攻撃対象の選び方を返す。登録されていなければ既定のものを返す
+func lookupTargeting(name string) Targeting {
+	if t, ok := targetings[name]; ok {
+		return t
+	}
+	return targetings[DefaultTargeting]
+}
+
+// selectTarget は (x, y) から武器の攻撃範囲内にいる生存中の敵のうち、targeting で最も優先度が高いものを返す
+// 優先度が同じ敵の間では enemies の並び順で前にあるものを選ぶ
+func (w *World) selectTarget(x, y float64, weapon *Weapon, targeting Targeting) *Enemy {
+	var target *Enemy
+	bestScore := math.Inf(-1)
+	for _, i := range w.grid.query(nil, x, y, weapon.Range+2*w.grid.maxRadius) {
+		e := &w.enemies[i]
+		if !e.active || !weapon.inRange(x-e.x, y-e.y) {
+			continue
+		}
+		if score := targeting.Score(w, x, y, e); target == nil || score > bestScore {
+			target = e
+			bestScore = score
+		}
+	}
+	return target
+}
@@ -0,0 +1,43 @@
+package sim
+
+import "testing"
+
+func TestTargetings(t *testing.T) {
+	w := newCombatWorld()
+	// (300, 300) にいるユニットの攻撃範囲に a, tank, runner が入り、boss は範囲外にいる
+	enemies := map[string]Point{
+		"a":      {320, 300}, // 最も近い
+		"tank":   {200, 300}, // HP と報酬が最も多い
+		"runner": {380, 360}, // 本拠地に最も近く、HP が最も低い
+		"boss":   {300, 500},
+	}
+	for _, id := range []string{"a", "tank", "runner", "boss"} {
+		archetype, _ := archetypes.Lookup(id)
+		w.addEnemy(NewEnemy(archetype, enemies[id].X, enemies[id].Y))
+	}
+	w.grid.rebuild(w.enemies)
+	weapon := Weapon{Range: 150}
+
+	for _, tt := range []struct {
+		targeting string
+		want      string
+	}{
+		{TargetNearest, "a"},
+		{TargetFirst, "runner"},
+		{TargetStrongest, "tank"},
+		{TargetWeakest, "runner"},
+		{TargetReward, "tank"},
+	} {
+		t.Run(tt.targeting, func(t *testing.T) {
+			target := w.selectTarget(300, 300, &weapon, lookupTargeting(tt.targeting))
+			if target == nil || target.archetype.ID != tt.want {
+				t.Errorf("target = %v, want %s", target, tt.want)
+			}
+		})
+	}
+
+	// 範囲内に敵がいなければ何も選ばない
+	if target := w.selectTarget(600, 50, &weapon, lookupTargeting(TargetStrongest)); target != nil {
+		t.Errorf("target out of range = %s, want none", target.archetype.ID)
+	}
+}
@@ -0,0 +1,192 @@
+package sim
+
+import (
+	"errors"
+	"fmt"
+)
+
+// タワーを置くグリッドの 1 マスの大きさ
+const BuildCellSize = 32
+
+// タワーを置けるグリッドのマス数。フィールドからはみ出すマスは使わない
+const (
+	BuildGridCols = FieldWidth / BuildCellSize
+	BuildGridRows = FieldHeight / BuildCellSize
+)
+
+// TowerCost はタワーを 1 つ置くのに必要なお金
+const TowerCost = 50
+
+// タワーの半径。マスの中央に置く
+const towerRadius = 12
+
+// TowerRange はタワーの攻撃範囲（半径）を返す
+func TowerRange() float64 {
+	return unitWeapons.Tower.Range
+}
+
+// Tower はグリッドのマスに固定して置く、移動しない攻撃ユニット
+// タワーを置いたマスは敵が通れなくなる
+type Tower struct {
+	id        EntityID
+	col, row  int
+	x, y      float64 // 左上の座標
+	weapon    Weapon
+	targeting string // 攻撃対象の選び方の名前
+
+	framesSinceLastBullet int
+}
+
+// TowerPlacement はタワーを置くマス
+type TowerPlacement struct {
+	Col int `json:"col"`
+	Row int `json:"row"`
+}
+
+// CellAt はフィールド上の座標 (x, y) を含むマスを返す
+func CellAt(x, y float64) (col, row int) {
+	return int(x) / BuildCellSize, int(y) / BuildCellSize
+}
+
+// CellRect はマスの左上の座標を返す
+func CellRect(col, row int) (x, y float64) {
+	return float64(col * BuildCellSize), float64(row * BuildCellSize)
+}
+
+// CanPlaceTower は (col, row) のマスにタワーを置けるかを調べ、置けない場合はその理由を返す
+func (w *World) CanPlaceTower(col, row int) error {
+	if col < 0 || col >= BuildGridCols || row < 0 || row >= BuildGridRows {
+		return errors.New("out of field")
+	}
+	for i := range w.towers {
+		if w.towers[i].col == col && w.towers[i].row == row {
+			return errors.New("occupied by a tower")
+		}
+	}
+	x, y := CellRect(col, row)
+	size := w.base.GetRadius() * 2
+	if rectsOverlap(x, y, BuildCellSize, BuildCellSize, w.base.x, w.base.y, size, size) {
+		return errors.New("overlaps the base")
+	}
+	c := row*BuildGridCols + col
+	if w.nav.blocked[c] {
+		return errors.New("blocked by an obstacle")
+	}
+	spawns := w.spawnPoints()
+	for _, p := range spawns {
+		if cellIndex(p.X, p.Y) == c {
+			return errors.New("blocks an enemy spawn point")
+		}
+	}
+	for i := range w.enemies {
+		e := &w.enemies[i]
+		if e.active && cellIndex(e.x+e.GetRadius(), e.y+e.GetRadius()) == c {
+			return errors.New("occupied by an enemy")
+		}
+	}
+	// 置いた後も、出現位置とフィールドにいる敵のすべてから本拠地にたどり着けなければならない
+	trial := w.nav.clone()
+	trial.block(c)
+	for _, p := range spawns {
+		if !trial.reachable(p.X, p.Y) {
+			return errors.New("blocks the enemy path")
+		}
+	}
+	for i := range w.enemies {
+		e := &w.enemies[i]
+		if e.active && !trial.reachable(e.x+e.GetRadius(), e.y+e.GetRadius()) {
+			return errors.New("blocks the enemy path")
+		}
+	}
+	if w.money < TowerCost {
+		return fmt.Errorf("not enough money (need $%d)", TowerCost)
+	}
+	return nil
+}
+
+// placeTower は (col, row) のマスにタワーを置く。置けない場合は何もしない
+func (w *World) placeTower(col, row int) {
+	if w.CanPlaceTower(col, row) != nil {
+		return
+	}
+	x, y := CellRect(col, row)
+	tower := Tower{
+		id:        w.newID(),
+		col:       col,
+		row:       row,
+		x:         x + BuildCellSize/2 - towerRadius,
+		y:         y + BuildCellSize/2 - towerRadius,
+		weapon:    unitWeapons.Tower,
+		targeting: DefaultTargeting,
+	}
+	w.money -= TowerCost
+	w.towerIndex[tower.id] = len(w.towers)
+	w.towers = append(w.towers, tower)
+	w.nav.block(row*BuildGridCols + col)
+	w.chase = nil
+}
+
+// spawnPoints はステージで敵が出現するすべての位置を返す
+func (w *World) spawnPoints() []Point {
+	var points []Point
+	for _, wave := range w.currentStage.Waves {
+		for _, spawn := range wave.EnemySpawns {
+			for n := 0; n < spawn.count(); n++ {
+				x, y := spawn.position(n)
+				points = append(points, Point{X: x, Y: y})
+			}
+		}
+	}
+	// エンドレスモードでは画面端のどこからでも敵が出現しうる
+	if endless := w.currentStage.Endless; endless != nil {
+		for _, edge := range endless.Edges {
+			edge, _ := edgePoints(edge)
+			points = append(points, edge...)
+		}
+	}
+	return points
+}
+
+// rectsOverlap は 2 つの矩形が重なっているかを返す
+func rectsOverlap(x1, y1, w1, h1, x2, y2, w2, h2 float64) bool {
+	return x1 < x2+w2 && x2 < x1+w1 && y1 < y2+h2 && y2 < y1+h1
+}
+
+func (t *Tower) ID() EntityID {
+	return t.id
+}
+
+func (t *Tower) GetX() float64 {
+	return t.x
+}
+
+func (t *Tower) GetY() float64 {
+	return t.y
+}
+
+func (t *Tower) GetRadius() float64 {
+	return towerRadius
+}
+
+func (t *Tower) GetPosition() (x, y int) {
+	return int(t.x), int(t.y)
+}
+
+func (t *Tower) GetSize() (width, height int) {
+	return towerRadius * 2, towerRadius * 2
+}
+
+// Cell はタワーを置いたマスを返す
+func (t *Tower) Cell() (col, row int) {
+	return t.col, t.row
+}
+
+// Weapon はタワーが装備している武器を返す
+func (t *Tower) Weapon() Weapon {
+	return t.weapon
+}
+
+// Targeting はタワーの攻撃対象の選び方の名前を返す
+func (t *Tower) Targeting() string {
+	return t.targeting
+}
@@ -0,0 +1,188 @@
+package sim
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+)
+
+// WallKind は壁の種類
+type WallKind string
+
+const (
+	WallSlow     WallKind = "slow"     // 触れた敵を一定時間鈍足にする（既定）
+	WallDamage   WallKind = "dot"      // 触れた敵に一定時間継続ダメージを与える
+	WallBlock    WallKind = "block"    // 敵が通り抜けられない。敵は壁を攻撃して壊すまで進めない
+	WallRedirect WallKind = "redirect" // 触れた敵を壁を引いた向きに押し流す
+)
+
+// 壁の種類ごとの性能
+type wallSpec struct {
+	hp         int      // 耐久度
+	costFactor int      // 長さあたりのコストの倍率
+	decays     bool     // 時間とともに耐久度が減るかどうか
+	effect     EffectID // 初めて触れた敵に与える状態効果
+}
+
+var wallSpecs = map[WallKind]wallSpec{
+	WallSlow:     {hp: 600, costFactor: 1, decays: true, effect: EffectSlow},
+	WallDamage:   {hp: 400, costFactor: 2, decays: true, effect: EffectBurn},
+	WallBlock:    {hp: 300, costFactor: 3, decays: false},
+	WallRedirect: {hp: 400, costFactor: 2, decays: true},
+}
+
+// WallKinds は壁の種類の一覧を返す
+func WallKinds() []WallKind {
+	return []WallKind{WallSlow, WallDamage, WallBlock, WallRedirect}
+}
+
+// 壁のコスト。長さ WallPixelsPerMoney ピクセルごとに、壁の種類ごとの倍率の分だけお金がかかる
+const WallPixelsPerMoney = 10
+
+// WallInkPerWave はウェーブごとに引ける壁の長さの合計（ピクセル）
+const WallInkPerWave = 400
+
+// これより短い壁は引けない
+const minWallLength = 10
+
+// 敵が触れている間、1 フレームごとに減る壁の耐久度
+const wallContactWear = 1
+
+// redirect の壁が敵を押し流す速さ（1 フレームあたりのピクセル数）
+const wallRedirectSpeed = 1.5
+
+type Wall struct {
+	id             string
+	kind           WallKind
+	x1, y1, x2, y2 float64
+	hp             int
+}
+
+// WallPlacement は引く壁の種類と両端の座標
+type WallPlacement struct {
+	Kind WallKind `json:"kind,omitempty"` // 省略時は slow
+	X1   float64  `json:"x1"`
+	Y1   float64  `json:"y1"`
+	X2   float64  `json:"x2"`
+	Y2   float64  `json:"y2"`
+}
+
+// Length は壁の長さを返す
+func (p WallPlacement) Length() float64 {
+	return math.Hypot(p.X2-p.X1, p.Y2-p.Y1)
+}
+
+// WallCost は kind の壁を長さ length だけ引くのに必要なお金を返す
+func WallCost(kind WallKind, length float64) int {
+	return int(math.Ceil(length/WallPixelsPerMoney)) * wallSpecs[kind].costFactor
+}
+
+// NewWall は (x1, y1) から (x2, y2) までの kind の壁を生成する
+func NewWall(id string, kind WallKind, x1, y1, x2, y2 float64) Wall {
+	return Wall{id: id, kind: kind, x1: x1, y1: y1, x2: x2, y2: y2, hp: wallSpecs[kind].hp}
+}
+
+// Endpoints は壁の両端の座標を返す
+func (w *Wall) Endpoints() (x1, y1, x2, y2 float64) {
+	return w.x1, w.y1, w.x2, w.y2
+}
+
+// Kind は壁の種類を返す
+func (w *Wall) Kind() WallKind {
+	return w.kind
+}
+
+// HP は壁の残りの耐久度を返す
+func (w *Wall) HP() int {
+	return w.hp
+}
+
+// MaxHP は壁の耐久度の最大値を返す
+func (w *Wall) MaxHP() int {
+	return wallSpecs[w.kind].hp
+}
+
+// direction は壁を引いた向きの単位ベクトルを返す
+func (w *Wall) direction() (dx, dy float64) {
+	length := math.Hypot(w.x2-w.x1, w.y2-w.y1)
+	if length == 0 {
+		return 0, 0
+	}
+	return (w.x2 - w.x1) / length, (w.y2 - w.y1) / length
+}
+
+// crosses は (x1, y1) から (x2, y2) への移動が壁を横切るかを返す
+func (w *Wall) crosses(x1, y1, x2, y2 float64) bool {
+	return lineIntersectsLine(w.x1, w.y1, w.x2, w.y2, x1, y1, x2, y2)
+}
+
+// CanPlaceWall は壁を引けるかを調べ、引けない場合はその理由を返す
+func (w *World) CanPlaceWall(p WallPlacement) error {
+	if _, ok := wallSpecs[wallKindOrDefault(p.Kind)]; !ok {
+		return fmt.Errorf("unknown wall kind %q", p.Kind)
+	}
+	if !inField(p.X1, p.Y1) || !inField(p.X2, p.Y2) {
+		return errors.New("out of field")
+	}
+	length := p.Length()
+	if length < minWallLength {
+		return errors.New("too short")
+	}
+	if length > w.ink {
+		return fmt.Errorf("not enough ink (%d px left)", int(w.ink))
+	}
+	if cost := WallCost(wallKindOrDefault(p.Kind), length); w.money < cost {
+		return fmt.Errorf("not enough money (need $%d)", cost)
+	}
+	return nil
+}
+
+// placeWall はお金とインクを使って壁を引く。引けない場合は何もしない
+func (w *World) placeWall(p WallPlacement) {
+	if w.CanPlaceWall(p) != nil {
+		return
+	}
+	kind := wallKindOrDefault(p.Kind)
+	length := p.Length()
+	w.money -= WallCost(kind, length)
+	w.ink -= length
+	w.AddWall(NewWall(strconv.Itoa(int(w.newID())), kind, p.X1, p.Y1, p.X2, p.Y2))
+}
+
+func wallKindOrDefault(kind WallKind) WallKind {
+	if kind == "" {
+		return WallSlow
+	}
+	return kind
+}
+
+// updateWalls は時間による壁の劣化を進め、耐久度がなくなった壁を取り除く
+func (w *World) updateWalls() {
+	walls := w.walls[:0]
+	for _, wall := range w.walls {
+		if wallSpecs[wall.kind].decays {
+			wall.hp--
+		}
+		if wall.hp > 0 {
+			walls = append(walls, wall)
+		}
+	}
+	w.walls = walls
+}
+
+// blockingWall は敵が (fromX, fromY) から今の位置に移動したときに横切る block の壁を返す
+// 壁を無視する種類の敵は塞がれない
+func (w *World) blockingWall(enemy *Enemy, fromX, fromY float64) *Wall {
+	if enemy.archetype.HasFlag(FlagIgnoreWalls) {
+		return nil
+	}
+	r := enemy.GetRadius()
+	for i := range w.walls {
+		wall := &w.walls[i]
+		if wall.kind == WallBlock && wall.crosses(fromX+r, fromY+r, enemy.x+r, enemy.y+r) {
+			return wall
+		}
+	}
+	return nil
+}
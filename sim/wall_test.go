@@ -0,0 +1,31 @@
+package sim
+
+import "testing"
+
+// block の壁は、塞がれた敵の武器の発射間隔ごとに攻撃力の分だけ削られる
+func TestBlockWallHoldsUntilDestroyedByAttacks(t *testing.T) {
+	w := NewWorld(Stage{Base: BaseConfig{X: 600, Y: 440, HP: 20}, Waves: []Wave{{TotalFrames: 1}}}, 1)
+	w.players = nil
+	w.AddWall(NewWall("block", WallBlock, 200, 0, 200, FieldHeight))
+	archetype, _ := archetypes.Lookup("a")
+	w.addEnemy(NewEnemy(archetype, 100, 100))
+
+	firstHit := -1
+	for w.tick < 20000 && len(w.walls) > 0 {
+		w.Step(Input{})
+		if firstHit == -1 && w.walls[0].hp < wallSpecs[WallBlock].hp {
+			firstHit = w.tick
+		}
+		if e := &w.enemies[0]; e.x+e.GetRadius() > 200 {
+			t.Fatalf("tick %d: enemy passed the block wall", w.tick)
+		}
+	}
+	if firstHit == -1 || len(w.walls) > 0 {
+		t.Fatalf("wall was not destroyed (first hit at %d)", firstHit)
+	}
+	weapon := archetype.Weapon
+	hits := wallSpecs[WallBlock].hp / weapon.Damage
+	if got, want := w.tick-firstHit, (hits-1)*weapon.Cooldown; got != want {
+		t.Errorf("wall held for %d ticks after the first hit, want %d", got, want)
+	}
+}
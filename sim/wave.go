@@ -0,0 +1,269 @@
+package sim
+
+// 敵を出現させる画面端
+const (
+	EdgeTop    = "top"
+	EdgeBottom = "bottom"
+	EdgeLeft   = "left"
+	EdgeRight  = "right"
+)
+
+type EnemySpawnInfo struct {
+	SpawnFrame int     `json:"spawn_frame"`        // 何フレーム後に敵をスポーンさせるか
+	Enemy      string  `json:"enemy"`              // 出現させる敵の種類
+	X          float64 `json:"x"`                  // 出現位置の X 座標
+	Y          float64 `json:"y"`                  // 出現位置の Y 座標
+	Edge       string  `json:"edge,omitempty"`     // 指定した場合は X, Y の代わりにこの画面端に沿って出現させる
+	Count      int     `json:"count,omitempty"`    // 出現させる数。0 の場合は 1 体
+	Interval   int     `json:"interval,omitempty"` // 複数体を出現させる場合の出現間隔（フレーム数）
+	Jitter     float64 `json:"jitter,omitempty"`   // 出現位置を各軸この範囲でランダムにずらす
+}
+
+// count は出現させる敵の数を返す
+func (s *EnemySpawnInfo) count() int {
+	if s.Count <= 0 {
+		return 1
+	}
+	return s.Count
+}
+
+// lastSpawnFrame は最後の 1 体を出現させるフレームを返す
+func (s *EnemySpawnInfo) lastSpawnFrame() int {
+	return s.SpawnFrame + (s.count()-1)*s.Interval
+}
+
+// position は n 体目（0 始まり）の敵の出現位置を返す
+// 画面端が指定されている場合は、その辺を等間隔に分割した位置に並べる
+func (s *EnemySpawnInfo) position(n int) (x, y float64) {
+	ratio := float64(n+1) / float64(s.count()+1)
+	switch s.Edge {
+	case EdgeTop:
+		return FieldWidth * ratio, 0
+	case EdgeBottom:
+		return FieldWidth * ratio, FieldHeight
+	case EdgeLeft:
+		return 0, FieldHeight * ratio
+	case EdgeRight:
+		return FieldWidth, FieldHeight * ratio
+	}
+	return s.X, s.Y
+}
+
+type Wave struct {
+	EnemySpawns []EnemySpawnInfo `json:"enemy_spawns"` // このウェーブでの敵の出現情報
+	TotalFrames int              `json:"total_frames"` // このウェーブの持続フレーム数（次のウェーブが開始するまでのフレーム数）
+}
+
+// BaseConfig はステージ開始時の本拠地の設定
+type BaseConfig struct {
+	X  float64 `json:"x"`
+	Y  float64 `json:"y"`
+	HP int     `json:"hp"`
+}
+
+// LoseConditions は本拠地の破壊以外のゲームオーバー条件
+type LoseConditions struct {
+	// leak_zone に入った敵の数がこの値に達するとゲームオーバー。0 の場合や leak_zone がない場合は判定しない
+	MaxReachedEnemies int `json:"max_reached_enemies"`
+	// 敵が入ると防衛線を抜けたものとして数え、フィールドから取り除く領域。省略可
+	LeakZone *Obstacle `json:"leak_zone,omitempty"`
+}
+
+type Stage struct {
+	Version        int            `json:"version"`             // ステージファイルのフォーマットのバージョン
+	ID             string         `json:"id"`                  // ステージを識別する ID
+	Name           string         `json:"name"`                // 表示用のステージ名
+	Base           BaseConfig     `json:"base"`                // 本拠地の設定
+	StartingMoney  int            `json:"starting_money"`      // 開始時の所持金
+	LoseConditions LoseConditions `json:"lose_conditions"`     // ゲームオーバー条件
+	Waves          []Wave         `json:"waves"`               // このステージにおける各ウェーブの情報
+	Obstacles      []Obstacle     `json:"obstacles,omitempty"` // 敵が通れない領域
+
+	// 指定した場合はエンドレスモードになり、waves の後に終わりなくウェーブを生成する
+	Endless *EndlessConfig `json:"endless,omitempty"`
+}
+
+// Obstacle はステージ上の矩形の領域。obstacles では敵が通れない領域を表す
+// 経路探索ではこの矩形に少しでも重なるマスを通れないものとして扱う
+type Obstacle struct {
+	X      float64 `json:"x"`
+	Y      float64 `json:"y"`
+	Width  float64 `json:"width"`
+	Height float64 `json:"height"`
+}
+
+// overlaps は矩形 (x, y, width, height) が障害物に重なっているかを返す
+func (o *Obstacle) overlaps(x, y, width, height float64) bool {
+	return rectsOverlap(x, y, width, height, o.X, o.Y, o.Width, o.Height)
+}
+
+// contains は点 (x, y) が矩形の中にあるかを返す
+func (o *Obstacle) contains(x, y float64) bool {
+	return x >= o.X && x < o.X+o.Width && y >= o.Y && y < o.Y+o.Height
+}
+
+// 次のウェーブを早めに呼んだときに、早めたフレーム 60 フレーム（1 秒）あたりにもらえるお金
+const nextWaveBonusPerSecond = 2
+
+// activeWave は敵の出現が続いているウェーブ
+type activeWave struct {
+	index int // 何番目のウェーブか（0 始まり）。エンドレスモードでは生成したウェーブも数える
+	frame int // ウェーブが始まってから経過したフレーム数
+}
+
+// updateWaves は始まっているウェーブの敵を出現させ、最後に始まったウェーブの時間が過ぎたら次のウェーブを始める
+// 次のウェーブを早めに呼んだ場合は、複数のウェーブの敵が並行して出現する
+func (w *World) updateWaves() {
+	for i := range w.activeWaves {
+		a := &w.activeWaves[i]
+		// 敵をスポーンさせるか確認
+		for _, spawnInfo := range w.wave(a.index).EnemySpawns {
+			for n := 0; n < spawnInfo.count(); n++ {
+				if spawnInfo.SpawnFrame+n*spawnInfo.Interval == a.frame {
+					x, y := spawnInfo.position(n)
+					x, y = w.jitter(x, y, spawnInfo.Jitter)
+					archetype, _ := archetypes.Lookup(spawnInfo.Enemy)
+					enemy := NewEnemy(archetype, x, y)
+					enemy.wave = a.index
+					w.addEnemy(enemy)
+				}
+			}
+		}
+		a.frame++
+	}
+
+	// 最後に始まったウェーブが終了したか確認
+	// 壁を引けるインクは、ウェーブが時間どおりに始まるときにだけ補充する
+	// 早めに呼んだときにも補充すると、何度も呼ぶだけでいくらでも壁を引けてしまう
+	if a := w.latestWave(); a != nil && a.frame >= w.wave(a.index).TotalFrames {
+		w.ink = WallInkPerWave
+		w.startNextWave()
+	}
+
+	// 敵が出現し終えたウェーブを取り除く
+	active := w.activeWaves[:0]
+	for _, a := range w.activeWaves {
+		if a.frame < w.wave(a.index).TotalFrames {
+			active = append(active, a)
+		}
+	}
+	w.activeWaves = active
+}
+
+// latestWave は最後に始まったウェーブを返す。敵が出現し終えている場合は nil を返す
+func (w *World) latestWave() *activeWave {
+	if len(w.activeWaves) == 0 {
+		return nil
+	}
+	return &w.activeWaves[len(w.activeWaves)-1]
+}
+
+// startNextWave は次のウェーブを始める
+func (w *World) startNextWave() {
+	if !w.hasWave(w.nextWave) {
+		return
+	}
+	w.prepareWave(w.nextWave)
+	w.activeWaves = append(w.activeWaves, activeWave{index: w.nextWave})
+	w.waveStarts = append(w.waveStarts, w.tick)
+	w.nextWave++
+}
+
+// callNextWave は最後に始まったウェーブの時間が過ぎるのを待たずに次のウェーブを始め、早めた時間に応じたお金をもらう
+func (w *World) callNextWave() {
+	if !w.CanCallNextWave() {
+		return
+	}
+	w.money += w.NextWaveBonus()
+	w.startNextWave()
+}
+
+// CanCallNextWave は次のウェーブを早めに呼べるかを返す
+// エンドレスモードでは、始まっているウェーブの敵が出現し終えるまで呼べない
+// 続けて呼ぶだけでいくらでもお金を稼いだり、ウェーブを進めたりできないようにする
+func (w *World) CanCallNextWave() bool {
+	if _, ok := w.NextWaveIn(); !ok {
+		return false
+	}
+	if w.Endless() {
+		for _, a := range w.activeWaves {
+			if w.pendingSpawns(a) > 0 {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// NextWaveBonus は今すぐ次のウェーブを呼んだときにもらえるお金を返す。呼べない場合は 0 を返す
+func (w *World) NextWaveBonus() int {
+	if !w.CanCallNextWave() {
+		return 0
+	}
+	ticks, _ := w.NextWaveIn()
+	return ticks * nextWaveBonusPerSecond / 60
+}
+
+// NextWaveIn は次のウェーブが始まるまでのティック数を返す。次のウェーブがない場合は ok が false になる
+func (w *World) NextWaveIn() (ticks int, ok bool) {
+	a := w.latestWave()
+	if a == nil || !w.hasWave(w.nextWave) {
+		return 0, false
+	}
+	return w.wave(a.index).TotalFrames - a.frame, true
+}
+
+// PendingSpawns はこれから出現する敵の数を、始まっているウェーブの残りとまだ始まっていないウェーブの分を合わせて返す
+func (w *World) PendingSpawns() int {
+	n := 0
+	for _, a := range w.activeWaves {
+		n += w.pendingSpawns(a)
+	}
+	// エンドレスモードでまだ生成していないウェーブの分は数えない
+	for _, wave := range w.currentStage.Waves[min(w.nextWave, len(w.currentStage.Waves)):] {
+		for _, spawn := range wave.EnemySpawns {
+			n += spawn.count()
+		}
+	}
+	return n
+}
+
+// pendingSpawns は始まっているウェーブ a でこれから出現する敵の数を返す
+func (w *World) pendingSpawns(a activeWave) int {
+	n := 0
+	for _, spawn := range w.wave(a.index).EnemySpawns {
+		for k := 0; k < spawn.count(); k++ {
+			if spawn.SpawnFrame+k*spawn.Interval >= a.frame {
+				n++
+			}
+		}
+	}
+	return n
+}
+
+// ClearedWaves は最初のウェーブから続けて片付けたウェーブの数を返す
+// 敵が出現し終え、そのウェーブで出現した敵がすべていなくなったウェーブを片付けたものとする
+func (w *World) ClearedWaves() int {
+	cleared := w.nextWave
+	for _, a := range w.activeWaves {
+		if w.pendingSpawns(a) > 0 {
+			cleared = min(cleared, a.index)
+		}
+	}
+	for _, enemy := range w.enemies {
+		if enemy.active {
+			cleared = min(cleared, enemy.wave)
+		}
+	}
+	return cleared
+}
+
+// Wave は始まったウェーブの数（今のウェーブの番号）を返す
+func (w *World) Wave() int {
+	return w.nextWave
+}
+
+// WaveStarts はそれぞれのウェーブが始まったティックを返す
+func (w *World) WaveStarts() []int {
+	return w.waveStarts
+}
@@ -0,0 +1,51 @@
+package sim
+
+import "testing"
+
+// 次のウェーブを早めに呼ぶと前のウェーブの敵も出現し続け、インクはウェーブが時間どおりに始まるときにだけ補充される
+func TestCallingNextWaveOverlapsWaves(t *testing.T) {
+	stage, err := LoadDefaultStage("sample")
+	if err != nil {
+		t.Fatal(err)
+	}
+	stage.Base.HP = 1 << 30
+	w := NewWorld(stage, 1)
+	w.players = nil
+	for w.Tick() < 70 {
+		w.Step(Input{})
+	}
+	w.ink = 0
+
+	// 1 つ目のウェーブの残り 230 フレーム分のお金をもらう
+	money := w.Money()
+	bonus := w.NextWaveBonus()
+	if want := (stage.Waves[0].TotalFrames - 70) * nextWaveBonusPerSecond / 60; bonus != want {
+		t.Errorf("bonus = %d, want %d", bonus, want)
+	}
+	w.Step(Input{Commands: []Command{CommandNextWave}})
+	if w.Wave() != 2 || len(w.activeWaves) != 2 || w.Money() != money+bonus {
+		t.Fatalf("wave = %d, active waves = %d, money = %d after calling, want 2, 2, %d", w.Wave(), len(w.activeWaves), w.Money(), money+bonus)
+	}
+	if w.Ink() != 0 {
+		t.Errorf("ink = %g after calling early, want 0", w.Ink())
+	}
+
+	// 2 つのウェーブの敵がすべて出現する
+	spawns := 0
+	for _, wave := range stage.Waves[:2] {
+		for _, spawn := range wave.EnemySpawns {
+			spawns += spawn.count()
+		}
+	}
+	started := w.Tick()
+	for w.Tick() < started+stage.Waves[1].TotalFrames-1 {
+		w.Step(Input{})
+	}
+	if len(w.Enemies()) != spawns || w.Wave() != 2 {
+		t.Errorf("enemies = %d, wave = %d before wave 3, want %d, 2", len(w.Enemies()), w.Wave(), spawns)
+	}
+	w.Step(Input{})
+	if w.Wave() != 3 || w.Ink() != WallInkPerWave {
+		t.Errorf("wave = %d, ink = %g when wave 3 started on its timer, want 3, %d", w.Wave(), w.Ink(), WallInkPerWave)
+	}
+}
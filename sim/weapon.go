@@ -0,0 +1,163 @@
+package sim
+
+import (
+	"bytes"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// ProjectileKind は武器が発射する弾の種類
+type ProjectileKind string
+
+const (
+	ProjectileHoming   ProjectileKind = "homing"   // ターゲットを追尾する弾
+	ProjectileStraight ProjectileKind = "straight" // 発射時のターゲットの位置に向けて直進する弾。外れることがある
+	ProjectilePiercing ProjectileKind = "piercing" // 直進し、Pierce 体の敵に命中するまで貫通する弾
+	ProjectileSplash   ProjectileKind = "splash"   // ターゲットを追尾し、命中した地点の周囲にダメージを与える弾
+	ProjectileChain    ProjectileKind = "chain"    // ターゲットを追尾し、命中した敵から近くの敵へ連鎖する弾
+)
+
+// straight は発射後にターゲットを追尾しない種類の弾かどうかを返す
+func (k ProjectileKind) straight() bool {
+	return k == ProjectileStraight || k == ProjectilePiercing
+}
+
+// TargetLostPolicy は追尾中のターゲットが倒されたときの弾の振る舞い
+type TargetLostPolicy string
+
+const (
+	TargetLostContinue TargetLostPolicy = "continue" // 直前の向きのまま直進する（既定）
+	TargetLostRetarget TargetLostPolicy = "retarget" // 最も近い別のターゲットを追尾する
+	TargetLostVanish   TargetLostPolicy = "vanish"   // その場で消える
+)
+
+// Weapon はユニットが装備する武器の性能を表す
+type Weapon struct {
+	Damage          int            `json:"damage"`           // 弾 1 発あたりの攻撃力
+	Range           float64        `json:"range"`            // 攻撃範囲（半径）
+	Cooldown        int            `json:"cooldown"`         // 弾の発射間隔（フレーム数）
+	ProjectileSpeed float64        `json:"projectile_speed"` // 弾の速度
+	Projectile      ProjectileKind `json:"projectile"`       // 弾の種類
+
+	// 追尾中のターゲットが倒されたときの振る舞い。省略時は continue
+	OnTargetLost TargetLostPolicy `json:"on_target_lost,omitempty"`
+
+	Pierce       int     `json:"pierce,omitempty"`        // piercing: 命中できる敵の数
+	SplashRadius float64 `json:"splash_radius,omitempty"` // splash: ダメージを与える半径
+	ChainCount   int     `json:"chain_count,omitempty"`   // chain: 連鎖する回数
+	ChainRange   float64 `json:"chain_range,omitempty"`   // chain: 連鎖できる敵までの距離
+
+	// 命中した相手に与える状態効果。RegisterEffect で登録した名前で指定する
+	Effects []EffectID `json:"effects,omitempty"`
+}
+
+// WeaponFormatVersion は読み込み可能な武器の設定ファイルのフォーマットのバージョン
+const WeaponFormatVersion = 1
+
+// UnitWeapons はプレイヤーユニットとタワーが装備する武器
+type UnitWeapons struct {
+	Player Weapon `json:"player"` // プレイヤーユニットが標準で装備する武器
+	Tower  Weapon `json:"tower"`  // タワーが装備する武器
+}
+
+// LoadUnitWeapons は JSON 形式の武器の設定を読み込み、内容を検証する
+func LoadUnitWeapons(r io.Reader) (*UnitWeapons, error) {
+	var file struct {
+		Version int `json:"version"`
+		UnitWeapons
+	}
+	dec := json.NewDecoder(r)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&file); err != nil {
+		return nil, fmt.Errorf("failed to decode weapons: %w", err)
+	}
+	if file.Version != WeaponFormatVersion {
+		return nil, fmt.Errorf("unsupported weapon format version %d (want %d)", file.Version, WeaponFormatVersion)
+	}
+	if err := file.Player.validate(); err != nil {
+		return nil, fmt.Errorf("player: %w", err)
+	}
+	if err := file.Tower.validate(); err != nil {
+		return nil, fmt.Errorf("tower: %w", err)
+	}
+	return &file.UnitWeapons, nil
+}
+
+// ゲームに同梱する武器の設定
+//
+//go:embed weapons.json
+var weaponsJSON []byte
+
+// unitWeapons はプレイヤーユニットとタワーに装備させる武器
+var unitWeapons = mustLoadDefaultUnitWeapons()
+
+func mustLoadDefaultUnitWeapons() *UnitWeapons {
+	weapons, err := LoadUnitWeapons(bytes.NewReader(weaponsJSON))
+	if err != nil {
+		panic(fmt.Sprintf("weapons.json: %v", err))
+	}
+	return weapons
+}
+
+// inRange は (dx, dy) だけ離れた位置が攻撃範囲内かを返す
+func (w *Weapon) inRange(dx, dy float64) bool {
+	return dx*dx+dy*dy < w.Range*w.Range
+}
+
+// ready は前回の発射から framesSinceLastBullet フレーム経過した時点で発射できるかを返す
+func (w *Weapon) ready(framesSinceLastBullet int) bool {
+	return framesSinceLastBullet >= w.Cooldown
+}
+
+// fire は (x, y) から target に向けて弾を発射する。hostile は敵が発射した弾かどうか
+func (w *Weapon) fire(x, y float64, target Entity, hostile bool) Bullet {
+	return NewBullet(x, y, target, w, hostile)
+}
+
+func (w *Weapon) validate() error {
+	if w.Damage < 0 {
+		return fmt.Errorf("damage must not be negative: %d", w.Damage)
+	}
+	if w.Range < 0 {
+		return fmt.Errorf("range must not be negative: %g", w.Range)
+	}
+	if w.Cooldown <= 0 {
+		return fmt.Errorf("cooldown must be positive: %d", w.Cooldown)
+	}
+	if w.ProjectileSpeed <= 0 {
+		return fmt.Errorf("projectile speed must be positive: %g", w.ProjectileSpeed)
+	}
+	switch w.OnTargetLost {
+	case "", TargetLostContinue, TargetLostRetarget, TargetLostVanish:
+	default:
+		return fmt.Errorf("unknown on_target_lost %q", w.OnTargetLost)
+	}
+	for _, id := range w.Effects {
+		if _, ok := effectSpecs[id]; !ok {
+			return fmt.Errorf("unknown effect %q", id)
+		}
+	}
+	switch w.Projectile {
+	case ProjectileHoming, ProjectileStraight:
+	case ProjectilePiercing:
+		if w.Pierce <= 0 {
+			return fmt.Errorf("pierce must be positive: %d", w.Pierce)
+		}
+	case ProjectileSplash:
+		if w.SplashRadius <= 0 {
+			return fmt.Errorf("splash radius must be positive: %g", w.SplashRadius)
+		}
+	case ProjectileChain:
+		if w.ChainCount <= 0 {
+			return fmt.Errorf("chain count must be positive: %d", w.ChainCount)
+		}
+		if w.ChainRange <= 0 {
+			return fmt.Errorf("chain range must be positive: %g", w.ChainRange)
+		}
+	default:
+		return fmt.Errorf("unknown projectile %q", w.Projectile)
+	}
+	return nil
+}
@@ -0,0 +1,33 @@
+package sim
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestLoadUnitWeapons(t *testing.T) {
+	const weapon = `{"damage": 2, "range": 150, "cooldown": 20, "projectile_speed": 6, "projectile": "splash", "splash_radius": 30}`
+	weapons, err := LoadUnitWeapons(strings.NewReader(`{"version": 1, "player": ` + weapon + `, "tower": ` + weapon + `}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if weapons.Tower.Projectile != ProjectileSplash || weapons.Tower.SplashRadius != 30 || weapons.Player.Range != 150 {
+		t.Errorf("weapons = %+v", weapons)
+	}
+
+	for _, tt := range []struct {
+		name, json, want string
+	}{
+		{"version", `{"version": 2, "player": ` + weapon + `, "tower": ` + weapon + `}`, "unsupported weapon format version 2"},
+		{"player", `{"version": 1, "player": {}, "tower": ` + weapon + `}`, "player: cooldown must be positive"},
+		{"tower", `{"version": 1, "player": ` + weapon + `, "tower": {"damage": 1, "range": 1, "cooldown": 1, "projectile_speed": 1, "projectile": "chain"}}`, "tower: chain count must be positive"},
+		{"unknown field", `{"version": 1, "player": ` + weapon + `, "tower": ` + weapon + `, "enemy": {}}`, "enemy"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := LoadUnitWeapons(strings.NewReader(tt.json))
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("LoadUnitWeapons() = %v, want an error containing %q", err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,377 @@
+package sim
+
+import (
+	"fmt"
+	"math"
+)
+
+// フィールド（情報表示領域を除いたプレイ領域）のサイズ
+const (
+	FieldWidth  = 640
+	FieldHeight = 520
+)
+
+// Status はシミュレーションの進行状況を表す
+type Status int
+
+const (
+	Running Status = iota // 進行中
+	Lost                  // ゲームオーバー
+	Won                   // ゲームクリア
+)
+
+func (s Status) String() string {
+	switch s {
+	case Running:
+		return "running"
+	case Lost:
+		return "lost"
+	case Won:
+		return "won"
+	}
+	return fmt.Sprintf("Status(%d)", int(s))
+}
+
+// World はゲームの状態を保持し、Step で 1 ティックずつ進める
+// ebiten には依存しないので、画面のない環境でもシミュレーションを回せる
+type World struct {
+	players        []Player
+	enemies        []Enemy
+	playerBullets  []Bullet
+	enemyBullets   []Bullet
+	status         Status
+	activeWaves    []activeWave // 敵の出現が続いているウェーブ。始まった順に並ぶ
+	nextWave       int          // 次に始めるウェーブが何番目か（0 始まり）
+	waveStarts     []int        // それぞれのウェーブが始まったティック
+	generatedWaves []Wave       // エンドレスモードで生成したウェーブ。ステージの waves の後に続く
+	currentStage   Stage
+	walls          []Wall
+	reachedEnemies int
+	kills          int // 倒した敵の数
+	money          int
+	base           *Base
+	tick           int // Step を呼び出した回数
+	rng            rng // シミュレーション内の乱数はすべてここから取り出す
+
+	fallen []Player // 倒されて復活を待っているプレイヤーユニット。復活が早い順に並ぶ
+	towers []Tower
+	nav    *flowField         // 敵が本拠地に向かう経路。障害物やタワーが置かれたら更新する
+	chase  map[int]*flowField // 自機を追いかける敵が使う経路。目標のマスごとに必要になったときに作り、タワーが置かれたら捨てる
+	ink    float64            // このウェーブで引ける壁の長さの残り
+
+	grid        *spatialGrid // 敵の位置による索引。毎ティック作り直す
+	lastID      EntityID
+	enemyIndex  map[EntityID]int // 敵の ID から enemies 内の位置を引く索引
+	playerIndex map[EntityID]int // プレイヤーユニットの ID から players 内の位置を引く索引
+	towerIndex  map[EntityID]int // タワーの ID から towers 内の位置を引く索引
+}
+
+// NewWorld はステージの開始時点のワールドを生成する
+// 同じステージとシードに同じ入力を与えれば、同じ結果が再現される
+func NewWorld(stage Stage, seed int64) *World {
+	w := &World{
+		rng:          newRNG(seed),
+		status:       Running,
+		base:         NewBase(stage.Base),
+		currentStage: stage,
+		money:        stage.StartingMoney,
+		grid:         newSpatialGrid(),
+		enemyIndex:   map[EntityID]int{},
+		playerIndex:  map[EntityID]int{},
+		towerIndex:   map[EntityID]int{},
+		nav:          newFlowField(stage),
+		ink:          WallInkPerWave,
+	}
+	w.base.id = w.newID()
+	w.startNextWave()
+	w.addPlayer(NewPlayer())
+	return w
+}
+
+func (w *World) Players() []Player       { return w.players }
+func (w *World) Enemies() []Enemy        { return w.enemies }
+func (w *World) PlayerBullets() []Bullet { return w.playerBullets }
+func (w *World) EnemyBullets() []Bullet  { return w.enemyBullets }
+func (w *World) Walls() []Wall           { return w.walls }
+func (w *World) Towers() []Tower         { return w.towers }
+func (w *World) Base() *Base             { return w.base }
+func (w *World) Money() int              { return w.money }
+func (w *World) Ink() float64            { return w.ink }
+func (w *World) Status() Status          { return w.status }
+func (w *World) Stage() Stage            { return w.currentStage }
+func (w *World) Tick() int               { return w.tick }
+func (w *World) ReachedEnemies() int     { return w.reachedEnemies }
+func (w *World) Kills() int              { return w.kills }
+
+// AddWall はフィールドに壁を追加する
+func (w *World) AddWall(wall Wall) {
+	w.walls = append(w.walls, wall)
+}
+
+// Step はシミュレーションを 1 ティック進める
+func (w *World) Step(in Input) {
+	if w.status != Running {
+		return
+	}
+	w.tick++
+
+	// 復活待ちのプレイヤーユニットの処理
+	w.updateRespawns()
+
+	// 本拠地が受けている状態効果の処理
+	w.base.effects.update(w, w.base)
+
+	// 敵の生成
+	w.updateWaves()
+
+	// ゲームオーバーの判定
+	if limit := w.currentStage.LoseConditions.MaxReachedEnemies; limit > 0 && w.reachedEnemies >= limit {
+		w.status = Lost
+	}
+
+	// すべてのウェーブが終了し、敵が全滅したときの処理（クリア）
+	if !w.hasWave(w.nextWave) && len(w.activeWaves) == 0 && len(w.enemies) == 0 {
+		w.status = Won
+	}
+
+	// 敵全体に対する処理
+	for i := range w.enemies {
+		enemy := &w.enemies[i]
+
+		// leak_zone に到達した敵に対する処理
+		if enemy.reached {
+			w.reachedEnemies++
+			enemy.reached = false
+			enemy.active = false
+		}
+
+		// base またはプレイヤーユニットに到達した敵に対する処理。動けない間は移動も攻撃もしない
+		if !enemy.effects.stunned() {
+			target := w.enemyTarget(enemy)
+			distX := target.GetX() - enemy.x
+			distY := target.GetY() - enemy.y
+
+			// 敵の攻撃範囲にターゲットが入っていたら攻撃を開始する。そうでなければターゲットを目指す。
+			weapon := &enemy.archetype.Weapon
+			if weapon.inRange(distX, distY) {
+				if weapon.ready(enemy.framesSinceLastBullet) {
+					// 弾を発射する
+					bullet := weapon.fire(enemy.x, enemy.y, target, true)
+					w.enemyBullets = append(w.enemyBullets, bullet)
+
+					enemy.framesSinceLastBullet = 0
+				}
+			} else {
+				// 敵を移動
+				w.moveEnemy(enemy, target)
+			}
+		}
+
+		enemy.Update(w)
+	}
+
+	// 壊れた壁を取り除く
+	w.updateWalls()
+
+	// 敵の移動が終わったので、当たり判定と攻撃範囲の検索に使う索引を作り直す
+	w.grid.rebuild(w.enemies)
+
+	// プレイヤーが敵に近づいたら自動的に攻撃する
+	for i := range w.players {
+		player := &w.players[i]
+		if !player.weapon.ready(player.framesSinceLastBullet) || player.effects.stunned() {
+			continue
+		}
+		// プレイヤーの攻撃範囲に敵が入っていたら攻撃する
+		if enemy := w.selectTarget(player.x, player.y, &player.weapon, lookupTargeting(player.targeting)); enemy != nil {
+			// 弾を発射する
+			bullet := player.weapon.fire(player.x, player.y, enemy, false)
+			w.playerBullets = append(w.playerBullets, bullet)
+
+			player.framesSinceLastBullet = 0
+		}
+	}
+
+	// タワーも攻撃範囲に敵が入っていたら自動的に攻撃する
+	for i := range w.towers {
+		tower := &w.towers[i]
+		tower.framesSinceLastBullet++
+		if !tower.weapon.ready(tower.framesSinceLastBullet) {
+			continue
+		}
+		if enemy := w.selectTarget(tower.x, tower.y, &tower.weapon, lookupTargeting(tower.targeting)); enemy != nil {
+			bullet := tower.weapon.fire(tower.x, tower.y, enemy, false)
+			w.playerBullets = append(w.playerBullets, bullet)
+
+			tower.framesSinceLastBullet = 0
+		}
+	}
+
+	// 入力された指示を各ユニットに伝える
+	for _, order := range in.Orders {
+		w.applyOrder(order)
+	}
+	for i := range w.players {
+		w.players[i].Update(w)
+	}
+
+	// 入力されたマスにタワーを置く
+	for _, placement := range in.Towers {
+		w.placeTower(placement.Col, placement.Row)
+	}
+
+	// 入力された壁を引く
+	for _, placement := range in.Walls {
+		w.placeWall(placement)
+	}
+
+	// 入力されたコマンドを実行する
+	for _, command := range in.Commands {
+		switch command {
+		case CommandRecoverHP:
+			w.base.recoverHP(w)
+		case CommandTrainUnit:
+			w.base.trainUnit(w)
+		case CommandHastenRespawn:
+			w.hastenRespawn()
+		case CommandNextWave:
+			w.callNextWave()
+		}
+	}
+
+	// プレイヤーの弾の更新と敵との当たり判定
+	var candidates []int
+	for i := range w.playerBullets {
+		bullet := &w.playerBullets[i]
+		bullet.Update(w)
+		if !bullet.active {
+			continue
+		}
+		candidates = w.grid.query(candidates[:0], bullet.x, bullet.y, w.grid.maxRadius+bulletRadius)
+		for _, j := range candidates {
+			enemy := &w.enemies[j]
+			if bullet.active && enemy.active && !bullet.hasHit(enemy) && enemy.IsHit(bullet.x, bullet.y) {
+				w.bulletHit(bullet, enemy)
+			}
+		}
+	}
+
+	// 敵の弾の更新と本拠地・プレイヤーユニットとの当たり判定
+	for i := range w.enemyBullets {
+		bullet := &w.enemyBullets[i]
+		bullet.Update(w)
+		if bullet.active && !bullet.hasHit(w.base) && w.base.IsHit(bullet.x, bullet.y) {
+			w.bulletHit(bullet, w.base)
+		}
+		for j := range w.players {
+			player := &w.players[j]
+			if bullet.active && player.HP > 0 && !bullet.hasHit(player) && player.IsHit(bullet.x, bullet.y) {
+				w.bulletHit(bullet, player)
+			}
+		}
+	}
+
+	// 倒されたプレイヤーユニットを復活待ちにする
+	w.removeFallenPlayers()
+
+	// 無効になった敵を削除
+	activeEnemies := w.enemies[:0]
+	for _, enemy := range w.enemies {
+		if enemy.active {
+			activeEnemies = append(activeEnemies, enemy)
+		}
+	}
+	w.enemies = activeEnemies
+	w.reindexEnemies()
+
+	// 無効になった弾を削除
+	{
+		activeBullets := w.playerBullets[:0]
+		for _, bullet := range w.playerBullets {
+			if bullet.active {
+				activeBullets = append(activeBullets, bullet)
+			}
+		}
+		w.playerBullets = activeBullets
+	}
+	// 無効になった弾を削除
+	{
+		activeBullets := w.enemyBullets[:0]
+		for _, bullet := range w.enemyBullets {
+			if bullet.active {
+				activeBullets = append(activeBullets, bullet)
+			}
+		}
+		w.enemyBullets = activeBullets
+	}
+}
+
+// jitter は (x, y) を各軸 ±amount の範囲でランダムにずらした座標を返す
+// ずらした結果がフィールドの外に出る場合はフィールド内に収める
+func (w *World) jitter(x, y, amount float64) (float64, float64) {
+	if amount <= 0 {
+		return x, y
+	}
+	x += (w.rng.Float64()*2 - 1) * amount
+	y += (w.rng.Float64()*2 - 1) * amount
+	return math.Max(0, math.Min(x, FieldWidth)), math.Max(0, math.Min(y, FieldHeight))
+}
+
+// clone はワールドの状態を複製する。リプレイのシークに使うスナップショットを作るためのもの
+// ステージや敵の種類など、シミュレーション中に書き換えないデータは複製元と共有する
+func (w *World) clone() *World {
+	c := *w
+	c.players = clonePlayers(w.players)
+	c.fallen = clonePlayers(w.fallen)
+	c.enemies = make([]Enemy, len(w.enemies))
+	for i, enemy := range w.enemies {
+		enemy.collidedWalls = append([]string(nil), enemy.collidedWalls...)
+		enemy.effects = enemy.effects.clone()
+		c.enemies[i] = enemy
+	}
+	c.playerBullets = cloneBullets(w.playerBullets)
+	c.enemyBullets = cloneBullets(w.enemyBullets)
+	c.activeWaves = append([]activeWave(nil), w.activeWaves...)
+	c.waveStarts = append([]int(nil), w.waveStarts...)
+	c.generatedWaves = append([]Wave(nil), w.generatedWaves...)
+	c.walls = append([]Wall(nil), w.walls...)
+	c.towers = append([]Tower(nil), w.towers...)
+	c.nav = w.nav.clone()
+	c.chase = nil // 経路は nav から作り直せるので、複製の間で共有しない
+	base := *w.base
+	base.effects = base.effects.clone()
+	c.base = &base
+	c.grid = newSpatialGrid()
+	c.enemyIndex = make(map[EntityID]int, len(w.enemyIndex))
+	for id, i := range w.enemyIndex {
+		c.enemyIndex[id] = i
+	}
+	c.playerIndex = make(map[EntityID]int, len(w.playerIndex))
+	for id, i := range w.playerIndex {
+		c.playerIndex[id] = i
+	}
+	c.towerIndex = make(map[EntityID]int, len(w.towerIndex))
+	for id, i := range w.towerIndex {
+		c.towerIndex[id] = i
+	}
+	return &c
+}
+
+func clonePlayers(players []Player) []Player {
+	c := make([]Player, len(players))
+	for i, player := range players {
+		player.waypoints = append([]Point(nil), player.waypoints...)
+		player.effects = player.effects.clone()
+		c[i] = player
+	}
+	return c
+}
+
+func cloneBullets(bullets []Bullet) []Bullet {
+	c := make([]Bullet, len(bullets))
+	for i, bullet := range bullets {
+		bullet.hits = append([]EntityID(nil), bullet.hits...)
+		c[i] = bullet
+	}
+	return c
+}
@@ -0,0 +1,153 @@
+package sim
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// 終わりのないステージなどを打ち切るティック数（60fps で約 5 分）
+const testMaxTicks = 18000
+
+// randomInput はテスト用に、r に応じてユニットへの指示・タワー・壁・コマンドを混ぜた入力を作る
+func randomInput(w *World, r *rng) Input {
+	var in Input
+	if r.Intn(10) == 0 {
+		var units []EntityID
+		for _, p := range w.Players() {
+			units = append(units, p.ID())
+		}
+		kinds := []OrderKind{OrderMove, OrderPatrol, OrderHold}
+		in.Orders = append(in.Orders, Order{
+			Kind:   kinds[r.Intn(len(kinds))],
+			Units:  units,
+			Target: Point{X: r.Float64() * FieldWidth, Y: r.Float64() * FieldHeight},
+			Queue:  r.Intn(2) == 0,
+		})
+	}
+	if r.Intn(100) == 0 {
+		in.Towers = append(in.Towers, TowerPlacement{Col: r.Intn(BuildGridCols), Row: r.Intn(BuildGridRows)})
+	}
+	if r.Intn(120) == 0 {
+		kinds := WallKinds()
+		x, y := r.Float64()*FieldWidth, r.Float64()*FieldHeight
+		in.Walls = append(in.Walls, WallPlacement{Kind: kinds[r.Intn(len(kinds))], X1: x, Y1: y, X2: x + 40, Y2: y + 40})
+	}
+	if r.Intn(60) == 0 {
+		commands := []Command{CommandRecoverHP, CommandTrainUnit, CommandHastenRespawn, CommandNextWave}
+		in.Commands = append(in.Commands, commands[r.Intn(len(commands))])
+	}
+	return in
+}
+
+// summary はワールドの状態を比較しやすい文字列にする
+func summary(w *World) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "tick=%d status=%s money=%d ink=%g base=%d wave=%d kills=%d reached=%d\n",
+		w.Tick(), w.Status(), w.Money(), w.Ink(), w.Base().HP, w.Wave(), w.Kills(), w.ReachedEnemies())
+	for _, p := range w.Players() {
+		fmt.Fprintf(&b, "player %d (%g, %g) hp=%d %v\n", p.ID(), p.x, p.y, p.HP, p.Effects())
+	}
+	for _, e := range w.Enemies() {
+		fmt.Fprintf(&b, "enemy %d %s (%g, %g) hp=%d %v\n", e.ID(), e.archetype.ID, e.x, e.y, e.HP, e.Effects())
+	}
+	for _, t := range w.Towers() {
+		fmt.Fprintf(&b, "tower %d (%d, %d)\n", t.id, t.col, t.row)
+	}
+	for _, wall := range w.Walls() {
+		fmt.Fprintf(&b, "wall %s %s hp=%d\n", wall.id, wall.kind, wall.hp)
+	}
+	fmt.Fprintf(&b, "bullets=%d/%d respawns=%v\n", len(w.PlayerBullets()), len(w.EnemyBullets()), w.RespawnTimers())
+	return b.String()
+}
+
+// record は stage を seed から入力を与えながら進め、進めたワールドとその記録を返す
+func record(t *testing.T, stage Stage, seed int64) (*World, *Replay) {
+	t.Helper()
+	w := NewWorld(stage, seed)
+	rec := NewRecorder(stage.ID, seed)
+	r := newRNG(seed)
+	for w.Status() == Running && w.Tick() < testMaxTicks {
+		in := randomInput(w, &r)
+		rec.Record(w.Tick(), in)
+		w.Step(in)
+	}
+	return w, rec.Replay()
+}
+
+func TestDefaultStagesRunHeadless(t *testing.T) {
+	stages, err := DefaultStages()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, stage := range stages {
+		t.Run(stage.ID, func(t *testing.T) {
+			w, _ := record(t, stage, 1)
+			if w.Status() == Running && !w.Endless() {
+				t.Errorf("stage did not end within %d ticks", testMaxTicks)
+			}
+			// 入力がなくても最後まで進む
+			idle := NewWorld(stage, 1)
+			for idle.Status() == Running && idle.Tick() < testMaxTicks {
+				idle.Step(Input{})
+			}
+			if idle.Status() == Running && !idle.Endless() {
+				t.Errorf("idle stage did not end within %d ticks", testMaxTicks)
+			}
+		})
+	}
+}
+
+func TestWorldIsDeterministic(t *testing.T) {
+	stages, err := DefaultStages()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, stage := range stages {
+		t.Run(stage.ID, func(t *testing.T) {
+			a, _ := record(t, stage, 7)
+			b, _ := record(t, stage, 7)
+			if got, want := summary(b), summary(a); got != want {
+				t.Errorf("same seed and inputs gave different worlds:\n%s\nwant:\n%s", got, want)
+			}
+		})
+	}
+}
+
+// leak_zone に入った敵は漏れとして数えられて取り除かれ、上限に達するとゲームオーバーになる
+// leak_zone がないステージでは漏れを数えない
+func TestLeakZone(t *testing.T) {
+	for _, tt := range []struct {
+		name       string
+		zone       *Obstacle
+		wantStatus Status
+		wantLeaks  int
+	}{
+		{"zone", &Obstacle{X: 320, Y: 0, Width: 32, Height: FieldHeight}, Lost, 2},
+		{"no zone", nil, Lost, 0},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			stage, err := LoadDefaultStage("sample")
+			if err != nil {
+				t.Fatal(err)
+			}
+			stage.LoseConditions = LoseConditions{MaxReachedEnemies: 2, LeakZone: tt.zone}
+			w := NewWorld(stage, 1)
+			w.players = nil
+			for w.Status() == Running && w.Tick() < testMaxTicks {
+				w.Step(Input{})
+				for _, e := range w.Enemies() {
+					if tt.zone != nil && e.x+e.GetRadius() >= tt.zone.X+tt.zone.Width {
+						t.Fatalf("tick %d: enemy %d passed the leak zone", w.Tick(), e.ID())
+					}
+				}
+			}
+			if w.Status() != tt.wantStatus || w.ReachedEnemies() != tt.wantLeaks {
+				t.Errorf("status = %s, leaks = %d, want %s, %d", w.Status(), w.ReachedEnemies(), tt.wantStatus, tt.wantLeaks)
+			}
+			if tt.zone == nil && w.Base().HP > 0 {
+				t.Errorf("base hp = %d, want the base destroyed", w.Base().HP)
+			}
+		})
+	}
+}
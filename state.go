@@ -0,0 +1,323 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/ebitenutil"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+	"github.com/pankona/generic-defence-game/sim"
+)
+
+// gameState は画面の状態。Game.setState で切り替える
+type gameState interface {
+	enter(g *Game) // この状態に切り替わったときに呼ばれる
+	exit(g *Game)  // 別の状態に切り替わるときに呼ばれる
+	update(g *Game)
+	draw(g *Game, screen *ebiten.Image)
+}
+
+// setState は画面の状態を切り替える
+func (g *Game) setState(s gameState) {
+	if g.state != nil {
+		g.state.exit(g)
+	}
+	g.state = s
+	s.enter(g)
+}
+
+// メニュー画面のボタンの大きさ
+const (
+	menuButtonWidth  = 160
+	menuButtonHeight = 36
+)
+
+// menuButton は画面の中央に並べるメニューのボタンを返す。i は上から何番目か
+func menuButton(i int, text string, action func(g *Game)) *Button {
+	return &Button{
+		action: action,
+		text:   []string{text},
+		x:      (screenWidth - menuButtonWidth) / 2,
+		y:      float64(menuTop + i*(menuButtonHeight+10)),
+		width:  menuButtonWidth,
+		height: menuButtonHeight,
+	}
+}
+
+// メニューのボタンを並べ始める Y 座標
+const menuTop = (screenHeight-infoAreaHeight)/2 + 40
+
+// updateMenu はメニューのボタンが押されていれば、そのボタンの処理を行う
+func (g *Game) updateMenu(buttons []*Button) {
+	for _, button := range buttons {
+		if g.pointer.pressedOn(button) {
+			g.pointer.consume()
+			button.action(g)
+			return
+		}
+	}
+}
+
+// drawMenu はメニューのボタンを描画する
+func (g *Game) drawMenu(screen *ebiten.Image, buttons []*Button) {
+	for _, button := range buttons {
+		g.drawButton(screen, button)
+	}
+}
+
+// drawCenteredText は画面の中央に message を表示する。line は中央から何行下に表示するか
+func drawCenteredText(screen *ebiten.Image, message string, line int) {
+	messageWidth := len(message) * 6 // 6 is the width of a character
+	messageX := (screenWidth - messageWidth) / 2
+	messageY := (screenHeight-infoAreaHeight)/2 + line*20
+	ebitenutil.DebugPrintAt(screen, message, messageX, messageY)
+}
+
+// titleState はタイトル画面
+type titleState struct {
+	buttons []*Button
+}
+
+func (s *titleState) enter(g *Game) {
+	s.buttons = []*Button{
+		menuButton(0, "Start", func(g *Game) { g.setState(&stageSelectState{}) }),
+	}
+}
+
+func (s *titleState) exit(g *Game) {}
+
+func (s *titleState) update(g *Game) {
+	if inpututil.IsKeyJustPressed(ebiten.KeyEnter) {
+		g.setState(&stageSelectState{})
+		return
+	}
+	g.updateMenu(s.buttons)
+}
+
+func (s *titleState) draw(g *Game, screen *ebiten.Image) {
+	drawCenteredText(screen, "Generic Defence Game", -2)
+	drawCenteredText(screen, "Click Start or press Enter", 0)
+	g.drawMenu(screen, s.buttons)
+}
+
+// stageSelectState は遊ぶステージを選ぶ画面
+type stageSelectState struct {
+	buttons []*Button
+	err     error // ステージを読み込めなかった場合のエラー
+}
+
+func (s *stageSelectState) enter(g *Game) {
+	stages, err := sim.DefaultStages()
+	if err != nil {
+		s.err = err
+		log.Printf("failed to load stages: %v", err)
+	}
+	stages = append(stages, g.extraStages...)
+	s.buttons = s.buttons[:0]
+	for i, stage := range stages {
+		stage := stage
+		s.buttons = append(s.buttons, menuButton(i, stage.Name, func(g *Game) {
+			g.reset(stage)
+			g.setState(&playingState{})
+		}))
+	}
+	s.buttons = append(s.buttons, menuButton(len(stages), "Back", func(g *Game) { g.setState(&titleState{}) }))
+}
+
+func (s *stageSelectState) exit(g *Game) {}
+
+func (s *stageSelectState) update(g *Game) {
+	if inpututil.IsKeyJustPressed(ebiten.KeyEscape) {
+		g.setState(&titleState{})
+		return
+	}
+	g.updateMenu(s.buttons)
+}
+
+func (s *stageSelectState) draw(g *Game, screen *ebiten.Image) {
+	drawCenteredText(screen, "Select Stage", 0)
+	if s.err != nil {
+		drawCenteredText(screen, s.err.Error(), 1)
+	}
+	g.drawMenu(screen, s.buttons)
+}
+
+// playingState はゲームをプレイしている状態
+type playingState struct{}
+
+func (s *playingState) enter(g *Game) {}
+
+func (s *playingState) exit(g *Game) {}
+
+func (s *playingState) update(g *Game) {
+	// ウィンドウやブラウザのタブからフォーカスが外れたら自動的に一時停止する
+	if inpututil.IsKeyJustPressed(ebiten.KeyP) || !ebiten.IsFocused() {
+		g.togglePause()
+		return
+	}
+	g.UpdateGame()
+	if g.world.Status() != sim.Running {
+		g.setState(&resultsState{})
+	}
+}
+
+func (s *playingState) draw(g *Game, screen *ebiten.Image) {
+	g.drawGame(screen)
+	drawInfoArea(screen)
+}
+
+// pausedState はゲームを一時停止している状態。ワールドは進めずに画面だけを表示する
+type pausedState struct {
+	resume  gameState // 再開したときに戻る状態
+	buttons []*Button
+}
+
+func (s *pausedState) enter(g *Game) {
+	s.buttons = []*Button{
+		menuButton(0, "Resume [P]", func(g *Game) { g.setState(s.resume) }),
+		menuButton(1, "Retry", (*Game).retry),
+		menuButton(2, "Back to menu", func(g *Game) { g.setState(&titleState{}) }),
+	}
+}
+
+func (s *pausedState) exit(g *Game) {}
+
+func (s *pausedState) update(g *Game) {
+	if inpututil.IsKeyJustPressed(ebiten.KeyP) {
+		g.setState(s.resume)
+		return
+	}
+	// 一時停止中も HUD のボタンで再開や速度の切り替えができる
+	// コマンドはワールドを進めないと実行できないので、画面側の処理を持つボタンだけを受け付ける
+	for _, button := range g.hudButtons {
+		if button.action != nil && g.pointer.pressedOn(button) {
+			g.pointer.consume()
+			button.action(g)
+			return
+		}
+	}
+	g.updateMenu(s.buttons)
+}
+
+func (s *pausedState) draw(g *Game, screen *ebiten.Image) {
+	g.drawGame(screen)
+	drawInfoArea(screen)
+	drawCenteredText(screen, "Paused", 0)
+	g.drawMenu(screen, s.buttons)
+}
+
+// resultsState はゲームオーバー・ゲームクリアの後に結果を表示する画面
+// 誤ってクリックしても結果が消えないよう、ボタンかキーでのみ次の画面に進む
+type resultsState struct {
+	world   *sim.World // 結果を表示するワールド。リプレイを見た後に戻ってきたときに使う
+	newBest bool       // 片付けたウェーブの数の最高記録を更新したかどうか
+	buttons []*Button
+}
+
+func (s *resultsState) enter(g *Game) {
+	if s.world != nil {
+		// リプレイから戻ってきた
+		g.world = s.world
+		return
+	}
+	s.world = g.world
+	s.newBest = g.recordBestWave()
+	g.selectUnits(nil)
+	g.placingTower, g.buildingWalls, g.drawingWall = false, false, false
+	s.buttons = []*Button{
+		menuButton(2, "Retry", (*Game).retry),
+		menuButton(3, "Back to menu", func(g *Game) { g.setState(&titleState{}) }),
+	}
+
+	// ゲームが終わったらリプレイを出力する。ブラウザではコンソールに表示される
+	log.Printf("replay (stage %s, %s):", g.world.Stage().ID, g.world.Status())
+	if err := g.recorder.Replay().Encode(os.Stdout); err != nil {
+		log.Printf("failed to write replay: %v", err)
+	}
+}
+
+func (s *resultsState) exit(g *Game) {}
+
+func (s *resultsState) update(g *Game) {
+	// R キーで今のプレイのリプレイを再生する
+	if inpututil.IsKeyJustPressed(ebiten.KeyR) {
+		if err := g.startPlayback(g.recorder.Replay()); err != nil {
+			log.Printf("failed to start replay: %v", err)
+		}
+		return
+	}
+	g.updateMenu(s.buttons)
+}
+
+func (s *resultsState) draw(g *Game, screen *ebiten.Image) {
+	g.drawGame(screen)
+	drawInfoArea(screen)
+
+	w := s.world
+	if w.Status() == sim.Won {
+		drawGameClear(screen)
+	} else {
+		drawGameOver(screen)
+	}
+	waves := fmt.Sprintf("Waves: %d/%d", w.Wave(), len(w.Stage().Waves))
+	if w.Endless() {
+		waves = fmt.Sprintf("Cleared waves: %d", w.ClearedWaves())
+	}
+	if s.newBest {
+		waves += "  New best!"
+	} else {
+		waves += fmt.Sprintf("  Best: %d", g.bestWaves[w.Stage().ID])
+	}
+	stats := []string{
+		fmt.Sprintf("Time: %s", formatTicks(w.Tick())),
+		waves,
+		fmt.Sprintf("Kills: %d  Leaks: %d", w.Kills(), w.ReachedEnemies()),
+		fmt.Sprintf("Base HP: %d  Money: %d", max(w.Base().HP, 0), w.Money()),
+	}
+	for i, line := range stats {
+		drawCenteredText(screen, line, i+1)
+	}
+	drawCenteredText(screen, "Press R to watch replay", len(stats)+1)
+	g.drawMenu(screen, s.buttons)
+}
+
+// replayingState はリプレイを再生している状態
+type replayingState struct {
+	back gameState  // 再生を終えたときに戻る状態
+	live *sim.World // 再生を始める前のワールド。再生したワールドを最高記録などに使わないよう、再生を終えたら戻す
+}
+
+func (s *replayingState) enter(g *Game) {}
+
+func (s *replayingState) exit(g *Game) {
+	g.playback = nil
+	g.world = s.live
+}
+
+func (s *replayingState) update(g *Game) {
+	if inpututil.IsKeyJustPressed(ebiten.KeyEscape) {
+		g.setState(s.back)
+		return
+	}
+	g.updatePlayback()
+}
+
+func (s *replayingState) draw(g *Game, screen *ebiten.Image) {
+	switch g.world.Status() {
+	case sim.Lost:
+		drawGameOver(screen)
+	case sim.Won:
+		drawGameClear(screen)
+	}
+	g.drawGame(screen)
+	drawInfoArea(screen)
+	g.drawPlayback(screen)
+}
+
+// retry は今のステージを最初からやり直す
+func (g *Game) retry() {
+	g.reset(g.world.Stage())
+	g.setState(&playingState{})
+}